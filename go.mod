@@ -1,3 +1,3 @@
 module github.com/mitranim/rf
 
-go 1.18
+go 1.23
@@ -11,7 +11,7 @@ func isFieldValid(val r.StructField) bool {
 	return val.Type != nil && len(val.Index) > 0
 }
 
-var typeFieldsCache = Cache{Func: func(typ r.Type) any {
+var typeFieldsCache = TypeCache[[]r.StructField]{Func: func(typ r.Type) []r.StructField {
 	typ = ValidateTypeStruct(typ)
 	out := make([]r.StructField, 0, typ.NumField())
 	for ind := range Iter(typ.NumField()) {
@@ -20,7 +20,7 @@ var typeFieldsCache = Cache{Func: func(typ r.Type) any {
 	return out
 }}
 
-var typeDeepFieldsCache = Cache{Func: func(typ r.Type) any {
+var typeDeepFieldsCache = TypeCache[[]r.StructField]{Func: func(typ r.Type) []r.StructField {
 	typ = ValidateTypeStruct(typ)
 	buf := make([]r.StructField, 0, typ.NumField())
 	path := make(Path, 0, expectedStructNesting)
@@ -45,10 +45,10 @@ func appendStructDeepFields(
 	}
 }
 
-var typeOffsetFieldsCache = Cache{Func: func(typ r.Type) any {
+var typeOffsetFieldsCache = TypeCache[map[uintptr][]r.StructField]{Func: func(typ r.Type) map[uintptr][]r.StructField {
 	typ = ValidateTypeStruct(typ)
 	if typ == nil {
-		return map[uintptr][]r.StructField(nil)
+		return nil
 	}
 
 	fields := TypeDeepFields(typ)
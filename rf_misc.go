@@ -5,37 +5,165 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 /*
 Tiny shortcut for caching arbitrary structures keyed by `reflect.Type`. Any
 reflection-based code that involves walking arbitrary structs should use
 `rf.Cache` to "compile" a specialized structure optimized for that particular
-traversal, and reuse it for subsequent invocations.
+traversal, and reuse it for subsequent invocations. For new code, prefer the
+generic `rf.TypeCache`, which avoids type assertions.
 */
 type Cache struct {
 	sync.Map
-	Func func(r.Type) any
+	Func    func(r.Type) any
+	pending sync.Map
 }
 
 /*
 Gets or creates the structure keyed by the type and generated by `self.Func`,
-which must be provided at construction time.
+which must be provided at construction time. For any given key, `self.Func` is
+called at most once, even when called concurrently. Concurrent callers for the
+same key wait for that call. If `self.Func` panics, nothing is stored, and the
+next caller tries again.
 */
 func (self *Cache) Get(key r.Type) any {
-	// Susceptible to "thundering herd". An improvement from no caching, but still
-	// not ideal.
+	for {
+		val, ok := self.Load(key)
+		if ok {
+			return val
+		}
+		self.init(key)
+	}
+}
 
-	val, ok := self.Load(key)
-	if !ok {
+func (self *Cache) init(key r.Type) {
+	once, _ := self.pending.LoadOrStore(key, new(sync.Once))
+
+	once.(*sync.Once).Do(func() {
+		defer self.pending.Delete(key)
+
+		// Another caller may have finished between our lookups.
+		_, ok := self.Load(key)
+		if ok {
+			return
+		}
+
+		var val any
 		if self.Func != nil {
 			val = self.Func(key)
 		}
 		self.Store(key, val)
+	})
+}
+
+/*
+Generic version of `rf.Cache`, for caching arbitrary structures keyed by
+`reflect.Type`. The zero value is ready to use, but `.Func` should be provided
+at construction time. Must not be copied after first use. Example:
+
+	var fieldNamesCache = rf.TypeCache[[]string]{Func: func(typ r.Type) []string {
+		...
+	}}
+
+	names := fieldNamesCache.Get(typ)
+
+For any given key, `.Func` is called at most once, even when `.Get` is called
+concurrently. Concurrent callers for the same key wait for that call, while
+callers for other keys proceed independently. If `.Func` panics, nothing is
+stored, and the next caller tries again. Lookups of existing entries don't lock.
+*/
+type TypeCache[A any] struct {
+	Func func(r.Type) A
+	dict sync.Map
+}
+
+// Gets or creates the structure keyed by the type and generated by `.Func`.
+func (self *TypeCache[A]) Get(key r.Type) A {
+	for {
+		entry := self.entry(key)
+		entry.Do(func() { self.init(key, entry) })
+		if entry.ok() {
+			return entry.Val
+		}
 	}
-	return val
 }
 
+/*
+Calls `.Get` for each given type, generating and caching the structures ahead
+of time. Useful for avoiding the cost of generating them later, for example
+during request handling.
+*/
+func (self *TypeCache[A]) Prewarm(keys ...r.Type) {
+	for _, key := range keys {
+		self.Get(key)
+	}
+}
+
+/*
+Deletes the structure cached for the given type, if any. The next call to
+`.Get` for that type will generate it again.
+*/
+func (self *TypeCache[A]) Delete(key r.Type) { self.dict.Delete(key) }
+
+/*
+Calls the given function for each cached structure, stopping when the function
+returns false. Skips structures which are still being generated. Like
+`sync.Map.Range`, doesn't provide a consistent snapshot when the cache is
+modified concurrently.
+*/
+func (self *TypeCache[A]) Range(fun func(r.Type, A) bool) {
+	if fun == nil {
+		return
+	}
+
+	self.dict.Range(func(key, val any) bool {
+		entry := val.(*typeCacheEntry[A])
+		if !entry.ok() {
+			return true
+		}
+		typ, _ := key.(r.Type)
+		return fun(typ, entry.Val)
+	})
+}
+
+func (self *TypeCache[A]) entry(key r.Type) *typeCacheEntry[A] {
+	val, ok := self.dict.Load(key)
+	if !ok {
+		val, _ = self.dict.LoadOrStore(key, new(typeCacheEntry[A]))
+	}
+	return val.(*typeCacheEntry[A])
+}
+
+/*
+Called at most once per entry. On panic, removes the entry, allowing the next
+caller to try again, unless the entry was already replaced.
+*/
+func (self *TypeCache[A]) init(key r.Type, entry *typeCacheEntry[A]) {
+	defer func() {
+		if !entry.ok() {
+			val, _ := self.dict.Load(key)
+			if val == entry {
+				self.dict.Delete(key)
+			}
+		}
+	}()
+
+	if self.Func != nil {
+		entry.Val = self.Func(key)
+	}
+	atomic.StoreUint32(&entry.Ok, 1)
+}
+
+type typeCacheEntry[A any] struct {
+	sync.Once
+	Val A
+	Ok  uint32
+}
+
+func (self *typeCacheEntry[A]) ok() bool { return atomic.LoadUint32(&self.Ok) != 0 }
+
 /*
 Zero-cost integer iterator. Usage:
 
@@ -427,7 +555,7 @@ func TypeFields(typ r.Type) []r.StructField {
 	if typ == nil {
 		return nil
 	}
-	return typeFieldsCache.Get(TypeDeref(typ)).([]r.StructField)
+	return typeFieldsCache.Get(TypeDeref(typ))
 }
 
 // Shortcut for `rf.TypeDeepFields(rf.DerefType(typ))`.
@@ -466,7 +594,7 @@ func TypeDeepFields(typ r.Type) []r.StructField {
 	if typ == nil {
 		return nil
 	}
-	return typeDeepFieldsCache.Get(TypeDeref(typ)).([]r.StructField)
+	return typeDeepFieldsCache.Get(TypeDeref(typ))
 }
 
 // Shortcut for `rf.TypeOffsetFields(rf.DerefType(typ))`.
@@ -488,7 +616,7 @@ func TypeOffsetFields(typ r.Type) map[uintptr][]r.StructField {
 	if typ == nil {
 		return nil
 	}
-	return typeOffsetFieldsCache.Get(TypeDeref(typ)).(map[uintptr][]r.StructField)
+	return typeOffsetFieldsCache.Get(TypeDeref(typ))
 }
 
 /*
@@ -502,7 +630,7 @@ type Path []int
 Returns a copy whose length and capacity are equal to the length of the
 original. Such copying is required when walking a struct type to build a
 collection of fields for later use (such representations should also be cached
-via `rf.Cache`).
+via `rf.TypeCache`).
 */
 func (self Path) Copy() Path {
 	if self == nil {
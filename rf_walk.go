@@ -1,6 +1,12 @@
 package rf
 
-import r "reflect"
+import (
+	"iter"
+	"path"
+	r "reflect"
+	"strings"
+	"sync"
+)
 
 // Flags constituting the return value of `rf.Filter`.
 // Unknown bits will be ignored.
@@ -21,6 +27,18 @@ const (
 	VisAll = 0b_1111_1111
 )
 
+// Flags constituting the return value of `rf.MapFilter`.
+const (
+	// Walk map keys.
+	VisKey = 0b_0000_0100
+
+	// Walk map values.
+	VisVal = 0b_0000_1000
+
+	// Walk both map keys and map values.
+	VisMap = VisKey | VisVal
+)
+
 /*
 Tool for implementing efficient reflect-based deep walking. Determines if a
 particular node should be visited during a walk, and how. This package provides
@@ -46,6 +64,11 @@ If the flag `rf.VisSelf` is set, we generate a walker that invokes
 `Visitor.Visit` on the current node. Otherwise the resulting walker will not
 visit the current node, and may possibly be nil.
 
+Maps are treated as leaf nodes unless the filter implements `rf.MapFilter`.
+The simplest way to enable map walking for an existing filter is `rf.Maps`:
+
+	rf.Or{rf.TypeFilter[string]{}, rf.Maps(rf.VisVal)}
+
 For technical reasons, all implementations of this interface must be values
 rather than references. For example, filters provided by this package must be
 used as values rather than pointers. The following is the CORRECT way to
@@ -92,12 +115,13 @@ same type for which the walker was generated. When using `rf.Walk` or
 the matching input first to `rf.GetWalker`, then to `.Walk`. For simplicity,
 walkers also assume that the visitor is non-nil.
 
-This package currently does NOT support walking into maps, for two reasons:
-unclear semantics and inefficiency. It's unclear if we should walk keys,
-values, or key-value pairs, and how that affects the rest of the walking API.
-Currently in Go 1.17, reflect-based map walking has horrible inefficiencies
-which can't be amortized by 3rd party code. It would be a massive performance
-footgun.
+This package supports walking into maps, but only when requested by the filter
+via `rf.MapFilter`; see `rf.Filter` and `rf.Maps`. Map keys and values are
+visited as non-addressable copies, like the dynamic values of interfaces.
+Visitors may read them, but can't modify them or take their addresses. Map
+iterators are pooled, but copying keys and values may allocate, and
+reflect-based map walking is slower than walking slices or structs, and should
+be enabled only where needed.
 
 This package does support walking into interface values included into other
 structures, but at an efficiency loss. In general, our walking mechanism relies
@@ -143,6 +167,25 @@ func WalkFunc(val r.Value, fil Filter, vis VisitorFunc) {
 	Walk(val, fil, vis)
 }
 
+/*
+Optional extension of `rf.Visitor` for post-order ("bottom-up") processing. When
+the visitor provided to `rf.Walk` implements this interface, `.Leave` is called
+for every visited node after walking its descendants, with the same inputs as
+the preceding `.Visit`. Calls to `.Visit` and `.Leave` are properly nested,
+like opening and closing brackets; for nodes without walked descendants,
+`.Leave` immediately follows `.Visit`. Useful for computing aggregates,
+normalizing children before parents, or closing scopes.
+
+Supported by `rf.Walk`, `rf.WalkOnce`, and functions built on them. Walkers
+returned by `rf.GetWalker` don't call `.Leave`, because `rf.Walk` uses
+separately generated and cached walkers for such visitors. Other visitors are
+unaffected by this feature and pay no additional cost.
+*/
+type LeaveVisitor interface {
+	Visitor
+	Leave(r.Value, r.StructField)
+}
+
 /*
 Takes an arbitrary value and performs deep traversal, invoking the visitor for
 each node allowed by the filter. Internally, uses `rf.GetWalker` to get or
@@ -153,24 +196,19 @@ it for future calls. This approach allows MUCH more efficient walking.
 If the input is zero/invalid/nil or the visitor is nil, this is a nop. For
 slightly better performance, pass a pointer to reduce copying.
 
+If the visitor implements `rf.LeaveVisitor`, its `.Leave` method is called after
+walking the descendants of each visited node.
+
 See also:
 
 	rf.Walker
 	rf.Filter
 	rf.Visitor
+	rf.LeaveVisitor
 	rf.GetWalker
 */
 func Walk(val r.Value, fil Filter, vis Visitor) {
-	if vis == nil {
-		return
-	}
-
-	wal := GetWalker(ValueType(val), fil)
-	if wal == nil {
-		return
-	}
-
-	wal.Walk(val, vis)
+	walkerCacheStatic.walkWithMode(val, fil, vis, 0)
 }
 
 /*
@@ -207,273 +245,2075 @@ func WalkPtrFunc(val any, fil Filter, vis VisitorFunc) {
 }
 
 /*
-Returns an `rf.Walker` for the given type with the given filter. Uses caching to
-avoid generating a walker more than once. Future calls with the same inputs
-will return the same walker instance. Returns nil if for this combination of
-type and filter, nothing will be visited. A nil filter is equivalent to a
-filter that always returns false, resulting in a nil walker.
+Variant of `rf.Walk` suitable for graphs: values where pointers may be shared or
+form cycles, such as `node.Next = node` or children pointing to their parents.
+Tracks the pointers encountered during this particular walk, and walks the
+target of any given pointer at most once, skipping repeated occurrences. The
+pointer itself is still visited every time, if allowed by the filter; only
+walking its target is skipped. Uses separately generated and cached walkers, and
+has a small overhead per pointer, so prefer `rf.Walk` when pointers are known to
+be unique.
+
+Only pointers are tracked. Cycles formed exclusively by other reference types,
+such as a slice of `any` which contains itself, are not detected.
 */
-func GetWalker(typ r.Type, fil Filter) Walker {
-	if typ == nil || fil == nil {
-		return nil
-	}
-	return walkerCacheStatic.getOrMake(typ, fil)
+func WalkOnce(val r.Value, fil Filter, vis Visitor) {
+	walkerCacheStatic.walkWithMode(val, fil, vis, walkModeOnce)
 }
 
 /*
-Shortcut for `rf.TrawlWith` without an additional filter. Takes an arbitrary
-source value and a pointer to an output slice. Walks the source value,
-appending all non-zero values of the matching type to the given slice.
+Variant of `rf.Walk` which treats structs embedded by value as parts of the
+enclosing struct, consistently with `rf.TypeDeepFields`. Instead of direct
+fields, walks the deep fields of each struct, as returned by
+`rf.TypeDeepFields` for that struct type. Value-embedded structs are not
+visited, and the filter is not consulted about them. Every
+`reflect.StructField` passed to the filter and the visitor is the
+corresponding entry of `rf.TypeDeepFields`, with `.Index` and `.Offset`
+relative to the nearest enclosing struct which is not embedded by value. This
+allows to join the results of a walk with the metadata of deep fields.
+
+Structs embedded by pointer are walked as regular fields, like in
+`rf.TypeDeepFields`, and their own fields are relative to them. Fields of
+private embedded structs are considered private, see `rf.PrivateFilter`. Like
+`rf.Walk`, supports `rf.LeaveVisitor`. Uses separately generated and cached
+walkers.
 */
-func Trawl[Src any, Out ~[]Elem, Elem any](src *Src, out *Out) {
-	TrawlWith(src, out, nil)
+func WalkFlat(val r.Value, fil Filter, vis Visitor) {
+	walkerCacheStatic.walkWithMode(val, fil, vis, walkModeFlat)
 }
 
 /*
-Shortcut for using `rf.Appender` and `rf.Walk` to trawl the provided "source"
-value to collect all non-zero values of a specific type into an "output" slice.
-The source value may be of arbitrary type. The output must be a non-nil pointer
-to a slice. The additional filter is optional.
-*/
-func TrawlWith[Src any, Out ~[]Elem, Elem any](src *Src, out *Out, fil Filter) {
-	if src == nil || out == nil {
-		return
+Variant of `rf.Visitor` that also receives the path from the root of the walked
+value to the current node. Used by `rf.WalkPath`. The path consists of struct
+field indexes (as in `reflect.StructField.Index`) and list element indexes.
+Pointers and interfaces are transparent and don't contribute to the path. Map
+keys and values don't contribute to the path either, because map keys are not
+integers. The root has an empty path.
+
+For example, for the following type:
+
+	type Order struct {
+		Id       string
+		Customer struct{ Email string }
 	}
 
-	/**
-	The unsafe cast is correct and safe. Workaround for Go limitations.
-	The following is equivalent and should work, but does not compile:
+When walking `[]Order`, the email of the customer of the 4th order has the path
+`[3 1 0]`.
 
-		appender := (*Appender[Elem])(out)
-	*/
-	appender := cast[*Appender[Elem]](out)
+The path is reused during the walk and must not be retained or modified by the
+visitor. To keep it, use `rf.Path.Copy`.
+*/
+type PathVisitor interface {
+	VisitPath(r.Value, r.StructField, Path)
+}
 
-	filter := MaybeAnd(appender.Filter(), fil)
-	Walk(r.ValueOf(src), filter, appender)
+/*
+Function type that implements `rf.PathVisitor`. Used by `rf.WalkPathFunc`.
+Converting a func to an interface value is alloc-free.
+*/
+type PathVisitorFunc func(r.Value, r.StructField, Path)
+
+// Implement `rf.PathVisitor` by calling itself.
+func (self PathVisitorFunc) VisitPath(val r.Value, field r.StructField, path Path) {
+	if self == nil {
+		return
+	}
+	self(val, field, path)
 }
 
-// Implementation of `rf.Filter` that always returns `rf.VisSelf`.
-type Self struct{}
+/*
+Variant of `rf.Walk` which provides the visitor with the path from the root of
+the given value to each visited node. See `rf.PathVisitor` for the path
+structure. Uses separately generated and cached walkers which maintain the path
+during the walk. Maintaining the path doesn't allocate: the path and other walk
+state are pooled between calls.
+*/
+func WalkPath(val r.Value, fil Filter, vis PathVisitor) {
+	if vis == nil {
+		return
+	}
 
-// Implement `rf.Filter`.
-func (Self) Visit(r.Type, r.StructField) byte { return VisSelf }
+	wal := getWalker(ValueType(val), fil, walkModePath)
+	if wal == nil {
+		return
+	}
 
-// Implementation of `rf.Filter` that always returns `rf.VisDesc`.
-type Desc struct{}
+	state := getPathWalkState(vis)
+	defer putPathWalkState(state)
+	wal.Walk(val, state)
+}
 
-// Implement `rf.Filter`.
-func (Desc) Visit(r.Type, r.StructField) byte { return VisDesc }
+// Shortcut for calling `rf.WalkPath` with a visitor func.
+func WalkPathFunc(val r.Value, fil Filter, vis PathVisitorFunc) {
+	// `WalkPath` can't detect this case. We have to check it here.
+	if vis == nil {
+		return
+	}
+	WalkPath(val, fil, vis)
+}
 
-// Implementation of `rf.Filter` that always returns `rf.VisBoth`.
-type Both struct{}
+/*
+Special errors which may be returned by `rf.VisitorErr` to control the walk.
+Similar to `fs.SkipDir` and `fs.SkipAll`.
+*/
+const (
+	// Don't walk the descendants of the current node, but continue the walk.
+	SkipDesc ErrStr = `[rf] skip descendants`
 
-// Implement `rf.Filter`.
-func (Both) Visit(r.Type, r.StructField) byte { return VisBoth }
+	// Stop the walk without an error.
+	SkipAll ErrStr = `[rf] skip all`
+)
 
-// Implementation of `rf.Filter` that always returns `rf.VisAll`.
-type All struct{}
+/*
+Variant of `rf.Visitor` that can control the walk by returning an error. Used by
+`rf.WalkErr`. Outcomes:
 
-// Implement `rf.Filter`.
-func (All) Visit(r.Type, r.StructField) byte { return VisAll }
+	* nil: continue the walk.
+	* `rf.SkipDesc`: don't walk the descendants of the current node.
+	* `rf.SkipAll`: stop the walk. `rf.WalkErr` returns nil.
+	* Any other error: stop the walk. `rf.WalkErr` returns that error.
+
+Returning `rf.SkipDesc` from a node without descendants has no effect.
+*/
+type VisitorErr interface {
+	VisitErr(r.Value, r.StructField) error
+}
 
 /*
-Implementation of `rf.Filter` that allows to visit values of this specific type.
-If the type is nil, this won't visit anything. The type may be either concrete
-or an interface. It also allows to visit descendants.
+Function type that implements `rf.VisitorErr`. Used by `rf.WalkFuncErr`.
+Converting a func to an interface value is alloc-free.
 */
-type TypeFilter[_ any] struct{}
+type VisitorFuncErr func(r.Value, r.StructField) error
 
-// Implement `rf.Filter`.
-func (TypeFilter[A]) Visit(typ r.Type, _ r.StructField) byte {
-	if typ == Type[A]() {
-		return VisBoth
+// Implement `rf.VisitorErr` by calling itself.
+func (self VisitorFuncErr) VisitErr(val r.Value, field r.StructField) error {
+	if self == nil {
+		return nil
 	}
-	return VisDesc
+	return self(val, field)
 }
 
 /*
-Implementation of `rf.Filter` that allows to visit values of the given
-`reflect.Kind`. If the kind is `reflect.Invalid`, this won't visit anything.
-
-Untested.
+Variant of `rf.Walk` which allows the visitor to skip descendants of any node,
+or to stop the walk early, by returning special errors or arbitrary errors.
+See `rf.VisitorErr` for details. Returns the first error returned by the
+visitor, other than `rf.SkipDesc` and `rf.SkipAll`. Uses separately generated
+and cached walkers.
 */
-type KindFilter r.Kind
+func WalkErr(val r.Value, fil Filter, vis VisitorErr) error {
+	if vis == nil {
+		return nil
+	}
 
-// Implement `rf.Filter`.
-func (self KindFilter) Visit(typ r.Type, _ r.StructField) byte {
-	if r.Kind(self) == typ.Kind() {
-		return VisBoth
+	wal := getWalker(ValueType(val), fil, walkModeCtl)
+	if wal == nil {
+		return nil
 	}
-	return VisDesc
+
+	state := walkState{ErrVis: vis}
+	wal.Walk(val, &state)
+	return state.Err
+}
+
+// Shortcut for calling `rf.WalkErr` with a visitor func.
+func WalkFuncErr(val r.Value, fil Filter, vis VisitorFuncErr) error {
+	// `WalkErr` can't detect this case. We have to check it here.
+	if vis == nil {
+		return nil
+	}
+	return WalkErr(val, fil, vis)
 }
 
 /*
-Implementation of `rf.Filter` that allows to visit values whose types implement
-the given interface BY POINTER. If the type is nil, this won't visit anything.
-The type must represent an interface, otherwise this will panic. The visitor
-must explicitly take value address:
+Optional extension of `rf.Visitor` which declares whether the visitor is safe
+for concurrent use. Used by `rf.WalkParallel`, which walks concurrently only
+when the visitor implements this interface and returns true.
+*/
+type ConcurrentVisitor interface {
+	Visitor
+	IsConcurrent() bool
+}
 
-	func visit(val r.Value, _ r.StructField) {
-		val.Addr().Interface().(SomeInterface).SomeMethod()
-	}
+/*
+Variant of `rf.Walk` which may walk the elements of large slices and arrays
+concurrently, using up to the given count of goroutines, including the current
+one. Intended for CPU-bound read-only passes over large lists, such as hashing
+or validation.
+
+Walks concurrently only when the visitor implements `rf.ConcurrentVisitor` and
+returns true. Otherwise, or when the worker count is below 2, this is
+equivalent to `rf.Walk`. Small lists are always walked in the current
+goroutine. The order of visits is unspecified. A panic in any goroutine is
+propagated to the caller after other goroutines are done. Like `rf.Walk`,
+supports `rf.LeaveVisitor`.
 */
-type IfaceFilter[_ any] struct{}
+func WalkParallel(val r.Value, fil Filter, vis Visitor, workers int) {
+	impl, _ := vis.(ConcurrentVisitor)
+	if workers < 2 || impl == nil || !impl.IsConcurrent() {
+		Walk(val, fil, vis)
+		return
+	}
 
-// Implement `rf.Filter`.
-func (IfaceFilter[A]) Visit(typ r.Type, _ r.StructField) byte {
-	return ifaceVisit(typ, Type[A](), VisBoth)
+	walkerCacheStatic.walkWithState(val, fil, walkModePar, walkState{
+		Vis: vis,
+		Sem: make(chan struct{}, workers-1),
+	})
 }
 
 /*
-Like `rf.IfaceFilter`, but visits either self or descendants, not both. In other
-words, once it finds a node that implements the given interface (by pointer),
-it allows to visit that node and stops there, without walking its descendants.
+Returns an `rf.Walker` for the given type with the given filter. Uses caching to
+avoid generating a walker more than once. Future calls with the same inputs
+will return the same walker instance. Returns nil if for this combination of
+type and filter, nothing will be visited. A nil filter is equivalent to a
+filter that always returns false, resulting in a nil walker.
 */
-type ShallowIfaceFilter[_ any] struct{}
-
-// Implement `rf.Filter`.
-func (ShallowIfaceFilter[A]) Visit(typ r.Type, _ r.StructField) byte {
-	return ifaceVisit(typ, Type[A](), VisSelf)
+func GetWalker(typ r.Type, fil Filter) Walker {
+	return getWalker(typ, fil, 0)
 }
 
 /*
-Implementation of `rf.Filter` that allows to visit values whose struct tag has a
-specific tag with a specific value, such as tag "json" with value "-". It also
-allows to visit descendants.
+Renders the walker generated by `rf.GetWalker` for the given type and filter as
+an indented tree, for debugging filters. Each line describes one node of the
+walker: how it's reached from its parent, its type, and the flags returned by
+the filter for that node. Labels:
+
+	* `.Name`  -- struct field
+	* `*`      -- pointer target
+	* `[]`     -- slice or array element
+	* `[key]`  -- map key
+	* `[val]`  -- map value
+
+Some nodes have additional marks:
+
+	* `private` -- private field walked due to `rf.PrivateFilter`
+	* `value`   -- node consulting `rf.ValueFilter` during the walk
+	* `cycle`   -- inner occurrence of a cyclic type, walked by the walker of
+	  the outer occurrence, whose descendants are listed there
+	* `dynamic` -- interface; the walker for its dynamic value is determined
+	  during the walk
+
+Nodes for which nothing would be visited are omitted, except for the root.
+Example:
+
+	type Person struct {
+		Name    string
+		Age     int
+		Emails  []string
+		Parent  *Person
+		Company any
+	}
 
-Known limitation: can't differentiate empty tag from missing tag.
+	fmt.Println(rf.Explain(rf.Type[*Person](), rf.TypeFilter[string]{}))
+
+Output:
+
+	*main.Person [desc]
+		* main.Person [desc]
+			.Name string [self|desc]
+			.Emails []string [desc]
+				[] string [self|desc]
+			.Parent *main.Person [desc]
+				* main.Person [desc]
+					.Name string [self|desc]
+					.Emails []string [desc]
+						[] string [self|desc]
+					.Parent *main.Person [desc] cycle
+					.Company interface {} [desc] dynamic
+			.Company interface {} [desc] dynamic
 */
-type TagFilter [2]string
-
-// Implement `rf.Filter`.
-func (self TagFilter) Visit(_ r.Type, field r.StructField) byte {
-	key, val := self[0], self[1]
-	if key != `` && field.Tag.Get(key) == val {
-		return VisBoth
+func Explain(typ r.Type, fil Filter) string {
+	if typ == nil {
+		return ``
 	}
-	return VisDesc
+
+	var buf walkExplain
+	buf.Filter = fil
+	buf.node(0, 0, ``, typ, r.StructField{}, GetWalker(typ, fil), false)
+	return buf.String()
+}
+
+func getWalker(typ r.Type, fil Filter, mode walkMode) Walker {
+	return walkerCacheStatic.getWalker(typ, fil, mode)
 }
 
 /*
-Implementation of `rf.Filter` that inverts the "self" bit of the inner filter,
-without changing the other flags. If the inner filter is nil, this always
-returns `rf.VisNone`.
+Cache of walkers generated for combinations of type and filter. The zero value
+is ready to use. Must not be copied after first use.
+
+Package-level functions such as `rf.GetWalker` and `rf.Walk` use a global cache
+which only grows. This is fine when types and filters are static, but programs
+which create types at runtime via `reflect.StructOf`, or create many short-lived
+filter values, may prefer to scope walker caches to a batch of work or a
+plugin lifetime, and discard them afterwards. The methods `.Clear`, `.Delete`,
+and the field `.Cap` allow to limit the size of a long-lived cache.
+
+Lookups are lock-free. Concurrent requests for a walker which is not yet cached
+wait for a single build instead of building the walker repeatedly.
+
+Walkers obtained from a cache remain valid after being removed from it.
+Walkers for interface values lazily get walkers for their dynamic values from
+the same cache, re-populating it if needed.
+
+The cache also stores the comparison plans used by `.Diff` and the copying
+plans used by `.CloneWith`, which are cached and removed like walkers, and
+included in `.Len` and `.Stats`.
 */
-type InvertSelf [1]Filter
+type WalkerCache struct {
+	// Maximum count of cached walkers. Zero or negative means unlimited. When
+	// adding a walker to a full cache, an arbitrary cached walker is evicted.
+	// The count includes walkers generated for internal walk modes, as well as
+	// nil walkers cached for types where nothing is visited. Comparison and
+	// copying plans are limited separately, with the same maximum.
+	Cap int
+
+	walkers refCache[Walker]
+	diffs   refCache[*diffPlan]
+	clones  refCache[*clonePlan]
+}
 
-// Implement `rf.Filter`.
-func (self InvertSelf) Visit(typ r.Type, field r.StructField) byte {
-	if self[0] == nil {
-		return VisNone
-	}
-	return self[0].Visit(typ, field) ^ VisSelf
+// Like `rf.GetWalker`, but uses this cache instead of the global cache.
+func (self *WalkerCache) GetWalker(typ r.Type, fil Filter) Walker {
+	return self.getWalker(typ, fil, 0)
 }
 
-/*
-Micro-optimization for `rf.And`. If the input has NO non-nil filters, returns
-nil. If the input has ONE non-nil filter, returns that filter, avoiding an
-allocation of `rf.And{}`. Otherwise combines the filters via `rf.And`.
-*/
-func MaybeAnd(vals ...Filter) Filter {
-	var out And
-	slice := maybeCombineFilters(vals, out[:0])
+// Like `rf.Walk`, but uses this cache instead of the global cache.
+func (self *WalkerCache) Walk(val r.Value, fil Filter, vis Visitor) {
+	self.walkWithMode(val, fil, vis, 0)
+}
 
-	switch len(slice) {
-	case 0:
-		return nil
-	case 1:
-		return slice[0]
-	default:
-		return out
-	}
+// Like `rf.Diff`, but uses this cache instead of the global cache.
+func (self *WalkerCache) Diff(a, b any, fil Filter) []Change {
+	return self.diff(a, b, fil)
 }
 
 /*
-Implementation of `rf.Filter` that combines other filters, AND-ing their outputs
-via `&`. Nil elements are ignored. If all elements are nil, the output is
-automatically `VisNone`.
+Like `rf.CloneWith`, but uses this cache instead of the global cache. Methods
+can't have type parameters, so this takes the destination and source as `any`.
+Panics unless they're pointers of the same type. Like `rf.CloneWith`, a nil
+destination pointer is a nop, and a nil source pointer zeroes the destination.
 */
-type And [8]Filter
-
-// Implement `rf.Filter`.
-func (self And) Visit(typ r.Type, field r.StructField) (vis byte) {
-	var found bool
+func (self *WalkerCache) CloneWith(dst, src any, fil Filter) {
+	valDst, valSrc := r.ValueOf(dst), r.ValueOf(src)
+	typDst, typSrc := ValueType(valDst), ValueType(valSrc)
 
-	for _, val := range self {
-		if val != nil {
-			if !found {
-				found = true
-				vis = val.Visit(typ, field)
-			} else {
-				vis &= val.Visit(typ, field)
-			}
-		}
+	if typDst == nil || typDst.Kind() != r.Ptr || typDst != typSrc {
+		panic(errCloneTypes(typDst, typSrc))
 	}
+	if valDst.IsNil() {
+		return
+	}
+	if valSrc.IsNil() {
+		valDst.Elem().SetZero()
+		return
+	}
+	self.cloneWith(valDst.Elem(), valSrc.Elem(), fil)
+}
 
-	return
+// Returns the count of cached walkers and plans.
+func (self *WalkerCache) Len() int {
+	return self.walkers.len() + self.diffs.len() + self.clones.len()
 }
 
 /*
-Micro-optimization for `rf.Or`. If the input has NO non-nil filters, returns
-nil. If the input has ONE non-nil filter, returns that filter, avoiding an
-allocation of `rf.Or{}`. Otherwise combines the filters via `rf.Or`.
+Removes all cached walkers and plans. Doesn't reset the stats. Walkers being
+built concurrently are added to the cache after clearing.
 */
-func MaybeOr(vals ...Filter) Filter {
-	var out Or
-	slice := maybeCombineFilters(vals, out[:0])
-
-	switch len(slice) {
-	case 0:
-		return nil
-	case 1:
-		return slice[0]
-	default:
-		return out
-	}
+func (self *WalkerCache) Clear() {
+	self.walkers.clear()
+	self.diffs.clear()
+	self.clones.clear()
 }
 
 /*
-Implementation of `rf.Filter` that combines other filters, OR-ing their outputs
-via `|`. Nil elements are ignored. If all elements are nil, the output is
-automatically `VisNone`.
+Removes all cached walkers and plans for the given type, for any filter.
+Walkers cached for other types which contain the given type, for example via
+fields or pointers, are unaffected. Returns the count of removed entries.
 */
-type Or [8]Filter
+func (self *WalkerCache) Delete(typ r.Type) int {
+	return self.walkers.delete(typ) + self.diffs.delete(typ) + self.clones.delete(typ)
+}
 
-// Implement `rf.Filter`.
-func (self Or) Visit(typ r.Type, field r.StructField) (vis byte) {
-	for _, val := range self {
-		if val != nil {
-			vis |= val.Visit(typ, field)
-		}
-	}
+/*
+Returns a snapshot of cache statistics, combined for walkers and plans. See
+`rf.WalkerCacheStats`.
+*/
+func (self *WalkerCache) Stats() (out WalkerCacheStats) {
+	self.walkers.stats(&out)
+	self.diffs.stats(&out)
+	self.clones.stats(&out)
 	return
 }
 
-// No-op implementation of both `rf.Visitor` that does nothing upon visit.
-type Nop struct{}
-
-// Implement `rf.Visitor`.
-func (Nop) Visit(r.Value, r.StructField) {}
+/*
+Statistics returned by `(*rf.WalkerCache).Stats`:
 
-// Implements `rf.Visitor` by appending visited non-zero elements.
-type Appender[A any] []A
+	* `.Len`       -- current count of cached walkers and plans
+	* `.Builds`    -- count of walkers and plans generated and added to the cache
+	* `.Evictions` -- count of walkers and plans evicted due to `.Cap`
 
-/*
-Implement `rf.Visitor` by appending the input value to the inner slice, if the
-value is non-zero.
+Entries removed via `.Clear` or `.Delete` are not counted as evictions.
 */
-func (self *Appender[A]) Visit(val r.Value, _ r.StructField) {
-	if self != nil && !val.IsZero() {
-		if val.CanAddr() {
-			*self = append(*self, *val.Addr().Interface().(*A))
-		} else {
-			*self = append(*self, val.Interface().(A))
-		}
-	}
+type WalkerCacheStats struct {
+	Len       int
+	Builds    int
+	Evictions int
 }
 
 /*
-Returns a filter that allows to visit only values suitable to be elements of the
-slice held by the appender.
+Shortcut for `rf.TrawlWith` without an additional filter. Takes an arbitrary
+source value and a pointer to an output slice. Walks the source value,
+appending all non-zero values of the matching type to the given slice.
+*/
+func Trawl[Src any, Out ~[]Elem, Elem any](src *Src, out *Out) {
+	TrawlWith(src, out, nil)
+}
+
+/*
+Shortcut for using `rf.Appender` and `rf.Walk` to trawl the provided "source"
+value to collect all non-zero values of a specific type into an "output" slice.
+The source value may be of arbitrary type. The output must be a non-nil pointer
+to a slice. The additional filter is optional.
+*/
+func TrawlWith[Src any, Out ~[]Elem, Elem any](src *Src, out *Out, fil Filter) {
+	if src == nil || out == nil {
+		return
+	}
+
+	/**
+	The unsafe cast is correct and safe. Workaround for Go limitations.
+	The following is equivalent and should work, but does not compile:
+
+		appender := (*Appender[Elem])(out)
+	*/
+	appender := cast[*Appender[Elem]](out)
+
+	filter := MaybeAnd(appender.Filter(), fil)
+	Walk(r.ValueOf(src), filter, appender)
+}
+
+/*
+Variant of `rf.Trawl` which skips values already present in the output slice,
+comparing them via `==`. Uses `rf.UniqueAppender`.
+*/
+func TrawlUnique[Src any, Out ~[]Elem, Elem comparable](src *Src, out *Out) {
+	if src == nil || out == nil {
+		return
+	}
+
+	var appender UniqueAppender[Elem]
+	appender.Appender = Appender[Elem](*out)
+	Walk(r.ValueOf(src), appender.Filter(), &appender)
+	*out = Out(appender.Appender)
+}
+
+/*
+Variant of `rf.Trawl` which finds the first non-zero value of the given type,
+stopping the walk early. The boolean indicates whether the value was found.
+Example:
+
+	id, ok := rf.TrawlFirst[Id](&val)
+*/
+func TrawlFirst[A, Src any](src *Src) (A, bool) {
+	var vis firstVisitor[A]
+	if src != nil {
+		// The visitor never returns errors other than `rf.SkipAll`.
+		_ = WalkErr(r.ValueOf(src), TypeFilter[A]{}, &vis)
+	}
+	return vis.Val, vis.Ok
+}
+
+/*
+Returns the count of non-zero values of the given type found by walking the
+source value, consistently with `rf.Trawl`. Example:
+
+	count := rf.Count[string](&val)
+*/
+func Count[A, Src any](src *Src) int {
+	var vis countVisitor
+	if src != nil {
+		Walk(r.ValueOf(src), TypeFilter[A]{}, &vis)
+	}
+	return int(vis)
+}
+
+/*
+True if walking the source value finds at least one non-zero value of the
+given type. Stops the walk at the first match. Shortcut for `rf.TrawlFirst`.
+*/
+func Exists[A, Src any](src *Src) bool {
+	_, ok := TrawlFirst[A](src)
+	return ok
+}
+
+/*
+Variant of `rf.Trawl` which appends pointers into the source value, rather than
+copies of values, allowing to mutate what was found. Unlike `rf.Trawl`,
+includes zero values. Values which are not addressable, such as those stored
+directly in interfaces or maps, are skipped. Uses `rf.PtrAppender`.
+*/
+func TrawlPtr[Src any, Out ~[]*Elem, Elem any](src *Src, out *Out) {
+	if src == nil || out == nil {
+		return
+	}
+	// See the comment in `rf.TrawlWith`.
+	appender := cast[*PtrAppender[Elem]](out)
+	Walk(r.ValueOf(src), appender.Filter(), appender)
+}
+
+/*
+Returns an iterator over the nodes visited by walking the given value with the
+given filter, in the same order as `rf.Walk`. Uses the cached walkers.
+Breaking out of the loop stops the walk early. Example:
+
+	for val, field := range rf.Values(r.ValueOf(&src), rf.TypeFilter[string]{}) {
+		fmt.Println(field.Name, val.String())
+	}
+*/
+func Values(val r.Value, fil Filter) iter.Seq2[r.Value, r.StructField] {
+	return func(yield func(r.Value, r.StructField) bool) {
+		// The visitor never returns errors other than `rf.SkipAll`.
+		_ = WalkErr(val, fil, valueYielder(yield))
+	}
+}
+
+/*
+Returns an iterator over pointers to all values of the given type found by
+walking the source value, including zero values, consistently with
+`rf.TrawlPtr`. Values which are not addressable, such as those stored directly
+in interfaces or maps, are skipped. Breaking out of the loop stops the walk
+early. Named "each" because `rf.All` is a filter. Example:
+
+	for ptr := range rf.Each[string](&src) {
+		*ptr = strings.TrimSpace(*ptr)
+	}
+*/
+func Each[A, Src any](src *Src) iter.Seq[*A] {
+	return func(yield func(*A) bool) {
+		if src != nil {
+			// The visitor never returns errors other than `rf.SkipAll`.
+			_ = WalkErr(r.ValueOf(src), TypeFilter[A]{}, ptrYielder[A](yield))
+		}
+	}
+}
+
+/*
+Single difference between two values, found by `rf.Diff`. The path is from
+the root of the compared values to the node which differs, with the same
+structure as in `rf.PathVisitor`. Old and new values belong to the first and
+second compared value respectively. For slice elements present in only one of
+the slices, the other value is invalid (zero `reflect.Value`).
+*/
+type Change struct {
+	Path Path
+	Old  r.Value
+	New  r.Value
+}
+
+/*
+Compares two values of the same type, walking them in lockstep, and returns
+the list of differences, in the same order as `rf.Walk` would visit them. The
+filter determines what's compared, just like it determines what's visited by
+`rf.Walk`:
+
+	* Nodes whose descendants are walked are compared structurally, by
+	  comparing their descendants. Differences in structure, such as a nil
+	  pointer versus a non-nil pointer, or interfaces with different dynamic
+	  types, are reported for the node itself. For interfaces, this happens
+	  only when the filter allows to compare either dynamic type.
+
+	* Other nodes allowed by `rf.VisSelf` are compared as a whole, via `==`
+	  for comparable values, and via `reflect.DeepEqual` otherwise.
+
+	* Everything else is ignored.
+
+Maps are always compared as a whole, because map keys can't be represented in
+paths. This applies to maps allowed by `rf.VisSelf`, and to maps allowed by
+`rf.VisDesc` whose keys or values contain anything compared by the filter,
+regardless of `rf.MapFilter`. Nil and empty maps are considered equal, like nil
+and empty slices, but maps nested in compared maps are compared via
+`reflect.DeepEqual`, which considers them different. Slices of different
+lengths are compared element by element, and the extra elements are reported
+individually. Equal pointers are not walked. Values of different types,
+including nil versus non-nil, are reported as a single change with an empty
+path. Example:
+
+	changes := rf.Diff(prev, next, rf.And{
+		rf.All{},
+		rf.Except{rf.FieldNameFilter(`UpdatedAt`)},
+	})
+
+Uses comparison plans generated for each combination of type and filter, and
+cached together with walkers. See `rf.WalkerCache`. Like `rf.Walk`, this
+doesn't support pointer cycles in compared values.
+*/
+func Diff(a, b any, fil Filter) []Change {
+	return walkerCacheStatic.diff(a, b, fil)
+}
+
+func (self *WalkerCache) diff(a, b any, fil Filter) []Change {
+	valA, valB := r.ValueOf(a), r.ValueOf(b)
+	typA, typB := ValueType(valA), ValueType(valB)
+
+	if typA != typB {
+		return []Change{{Old: valA, New: valB}}
+	}
+	if typA == nil || fil == nil {
+		return nil
+	}
+
+	var ref walkRef
+	ref.Filter = fil
+	ref.Type = typA
+
+	var tar differ
+	tar.diff(self.getDiffPlan(ref), valA, valB)
+	return tar.Out
+}
+
+/*
+Returns a deep copy of the given value, via `rf.CloneWith` with `rf.AllMaps`.
+Public fields, pointers, slices, arrays, maps and interfaces are copied
+recursively. Private fields are copied shallowly. If the source pointer is
+nil, returns a zero value. Example:
+
+	copy := rf.Clone(&val)
+*/
+func Clone[A any](src *A) (out A) {
+	CloneWith(&out, src, AllMaps{})
+	return
+}
+
+/*
+Copies the value at the source pointer to the destination pointer, copying
+nodes deeply or shallowly depending on the filter:
+
+	* Nodes allowed by `rf.VisDesc` are copied deeply: pointers, slices and
+	  maps are reallocated, and their descendants, as well as the fields of
+	  structs and the elements of arrays, are copied according to the filter.
+	  Interfaces are reallocated when their dynamic values are copied deeply.
+	  Map values are copied deeply only when the filter enables walking them
+	  via `rf.MapFilter`. Map keys are always copied shallowly.
+
+	* Nodes allowed only by `rf.VisSelf` are copied shallowly, sharing any
+	  memory referenced by them.
+
+	* Other nodes are zeroed.
+
+Private fields are copied shallowly, unless the filter allows them via
+`rf.PrivateFilter`. A nil filter makes a shallow copy. If the destination
+pointer is nil, this is a nop. If the source pointer is nil, the destination
+is zeroed. Example:
+
+	rf.CloneWith(&out, &src, rf.Or{
+		// Copy everything deeply, except for `.Cache` which is shared.
+		rf.And{rf.All{}, rf.Except{rf.FieldNameFilter(`Cache`)}},
+		rf.Self{},
+	})
+
+Uses copying plans generated for each combination of type and filter, and
+cached together with walkers. See `rf.WalkerCache`. Like `rf.Walk`, this
+doesn't support pointer cycles in copied values. Pointers shared between
+different parts of the source value are copied separately.
+*/
+func CloneWith[A any](dst, src *A, fil Filter) {
+	if dst == nil {
+		return
+	}
+	if src == nil {
+		*dst = *new(A)
+		return
+	}
+	if fil == nil {
+		*dst = *src
+		return
+	}
+	walkerCacheStatic.cloneWith(r.ValueOf(dst).Elem(), r.ValueOf(src).Elem(), fil)
+}
+
+// The destination must be settable.
+func (self *WalkerCache) cloneWith(dst, src r.Value, fil Filter) {
+	if fil == nil {
+		dst.Set(src)
+		return
+	}
+
+	var ref walkRef
+	ref.Filter = fil
+	ref.Type = dst.Type()
+	clone(self.getClonePlan(ref), dst, src)
+}
+
+/*
+Optional interface for masking values redacted by `rf.Redact`, instead of
+zeroing them. Must be implemented on pointer types, because redacted values
+are modified in place. Values whose types implement this interface via a value
+method are zeroed, because the method can't modify them. Example:
+
+	type Password string
+
+	func (self *Password) Redact() {
+		if *self != `` {
+			*self = `***`
+		}
+	}
+*/
+type Redactor interface{ Redact() }
+
+/*
+Redacts every value selected by the filter via `rf.VisSelf` in the target of
+the given pointer, which must be either a valid pointer or nil. Selected
+values are masked via `rf.Redactor` when their pointer type implements it, and
+zeroed otherwise. Descendants of selected values are not walked. Example:
+
+	rf.Redact(&payload, rf.Or{
+		rf.TagFilter{`secret`, `true`},
+		rf.TypeFilter[Password]{},
+	})
+
+Like `rf.WalkPtr`, validates the pointer, and uses the cached walkers. Unlike
+other walks, this also redacts the contents of interfaces holding non-pointer
+values, and the values of maps when the filter enables walking them via
+`rf.MapFilter`, by replacing them with redacted copies. Private fields are
+redacted only when the filter allows them via `rf.PrivateFilter`. See
+`rf.Redacted` for redacting a copy.
+*/
+func Redact(ptr any, fil Filter) {
+	if ptr == nil {
+		return
+	}
+
+	// Validate before early return.
+	tar := ValueDeref(ValidateValueKind(r.ValueOf(ptr), r.Ptr))
+
+	if fil == nil {
+		return
+	}
+	walkerCacheStatic.walkWithMode(tar, redactFilter{fil}, redactor{}, walkModeWrite)
+}
+
+/*
+Returns a redacted copy of the target of the given pointer, leaving the
+original untouched. Uses `rf.CloneWith` to make a deep copy of everything
+which may be redacted, including private fields allowed by the filter, then
+redacts the copy via `rf.Redact`. If the pointer is nil, returns a zero value.
+*/
+func Redacted[A any](src *A, fil Filter) (out A) {
+	if fil == nil {
+		CloneWith(&out, src, nil)
+		return
+	}
+	CloneWith(&out, src, redactCloneFilter{fil})
+	Redact(&out, fil)
+	return
+}
+
+/*
+Shortcut for `rf.TrawlParallelWith` without an additional filter. Variant of
+`rf.Trawl` which uses `rf.WalkParallel`. The order of the collected values is
+unspecified.
+*/
+func TrawlParallel[Src any, Out ~[]Elem, Elem any](src *Src, out *Out, workers int) {
+	TrawlParallelWith(src, out, nil, workers)
+}
+
+/*
+Variant of `rf.TrawlWith` which uses `rf.SyncAppender` and `rf.WalkParallel` to
+trawl the source value using up to the given count of goroutines. The order of
+the collected values is unspecified.
+*/
+func TrawlParallelWith[Src any, Out ~[]Elem, Elem any](
+	src *Src, out *Out, fil Filter, workers int,
+) {
+	if src == nil || out == nil {
+		return
+	}
+
+	var appender SyncAppender[Elem]
+	appender.Appender = Appender[Elem](*out)
+
+	filter := MaybeAnd(appender.Filter(), fil)
+	WalkParallel(r.ValueOf(src), filter, &appender, workers)
+	*out = Out(appender.Appender)
+}
+
+/*
+Walks the target of the given pointer via `rf.WalkPtr` and `rf.TypeFilter`,
+replacing every value of type `A` with the result of calling the given
+function on it. If the pointer or the function is nil, this is a nop. Example:
+
+	rf.Rewrite(&val, strings.TrimSpace)
+
+Values are replaced before walking their descendants, which are walked in the
+replaced value. Values which are not addressable, such as those stored
+directly in interfaces or maps, are skipped. Private fields are skipped, like
+in other walks. Like `rf.Walk`, this doesn't support pointer cycles.
+*/
+func Rewrite[A, Src any](src *Src, fun func(A) A) {
+	if src == nil || fun == nil {
+		return
+	}
+	WalkPtr(src, TypeFilter[A]{}, rewriter[A](fun))
+}
+
+/*
+Variant of `rf.Rewrite` which mutates every value of type `A` in place via the
+given function, which receives a pointer to the value. Example:
+
+	rf.RewritePtr(&val, func(val *time.Time) { *val = val.UTC() })
+*/
+func RewritePtr[A, Src any](src *Src, fun func(*A)) {
+	if src == nil || fun == nil {
+		return
+	}
+	WalkPtr(src, TypeFilter[A]{}, ptrRewriter[A](fun))
+}
+
+// Implementation of `rf.Filter` that always returns `rf.VisSelf`.
+type Self struct{}
+
+// Implement `rf.Filter`.
+func (Self) Visit(r.Type, r.StructField) byte { return VisSelf }
+
+// Implementation of `rf.Filter` that always returns `rf.VisDesc`.
+type Desc struct{}
+
+// Implement `rf.Filter`.
+func (Desc) Visit(r.Type, r.StructField) byte { return VisDesc }
+
+// Implementation of `rf.Filter` that always returns `rf.VisBoth`.
+type Both struct{}
+
+// Implement `rf.Filter`.
+func (Both) Visit(r.Type, r.StructField) byte { return VisBoth }
+
+// Implementation of `rf.Filter` that always returns `rf.VisAll`.
+type All struct{}
+
+// Implement `rf.Filter`.
+func (All) Visit(r.Type, r.StructField) byte { return VisAll }
+
+/*
+Variant of `rf.All` which also walks map keys and values. Implements
+`rf.MapFilter` by always returning `rf.VisMap`.
+*/
+type AllMaps struct{}
+
+// Implement `rf.Filter`.
+func (AllMaps) Visit(r.Type, r.StructField) byte { return VisAll }
+
+// Implement `rf.MapFilter`.
+func (AllMaps) VisitMap(r.Type, r.StructField) byte { return VisMap }
+
+/*
+Optional extension of `rf.Filter` which enables walking into maps. For each map
+type allowed by `rf.VisDesc`, walkers call `.VisitMap`, and walk the map's keys,
+values, or both, depending on the flags `rf.VisKey` and `rf.VisVal` in its
+result. Other flags are ignored. Filters which don't implement this interface
+never walk maps, regardless of what `.Visit` returns.
+
+The combinators `rf.And`, `rf.Or`, `rf.AndList`, `rf.OrList`, `rf.Xor`,
+`rf.Not`, `rf.InvertSelf`, `rf.AllowPrivate`, `rf.MaxDepth` implement this
+interface, combining the map flags of their inner filters via `|`. For a simple
+way to enable map walking for an existing filter, see `rf.Maps`.
+*/
+type MapFilter interface {
+	Filter
+	VisitMap(r.Type, r.StructField) byte
+}
+
+/*
+Implementation of `rf.Filter` and `rf.MapFilter` which doesn't allow to visit
+anything by itself, and enables map walking with the map flags from its own
+value. Intended for combining with other filters via `rf.Or` or `rf.And`:
+
+	rf.Or{rf.TypeFilter[string]{}, rf.Maps(rf.VisVal)} // Values only.
+	rf.Or{rf.TypeFilter[string]{}, rf.Maps(rf.VisKey)} // Keys only.
+	rf.Or{rf.TypeFilter[string]{}, rf.Maps(rf.VisMap)} // Keys and values.
+*/
+type Maps byte
+
+// Implement `rf.Filter`.
+func (Maps) Visit(r.Type, r.StructField) byte { return VisNone }
+
+// Implement `rf.MapFilter`.
+func (self Maps) VisitMap(r.Type, r.StructField) byte { return byte(self) & VisMap }
+
+/*
+Implementation of `rf.Filter` that allows to visit values of this specific type.
+If the type is nil, this won't visit anything. The type may be either concrete
+or an interface. It also allows to visit descendants.
+*/
+type TypeFilter[_ any] struct{}
+
+// Implement `rf.Filter`.
+func (TypeFilter[A]) Visit(typ r.Type, _ r.StructField) byte {
+	if typ == Type[A]() {
+		return VisBoth
+	}
+	return VisDesc
+}
+
+/*
+Implementation of `rf.Filter` that allows to visit values of the given
+`reflect.Kind`. If the kind is `reflect.Invalid`, this won't visit anything.
+*/
+type KindFilter r.Kind
+
+// Implement `rf.Filter`.
+func (self KindFilter) Visit(typ r.Type, _ r.StructField) byte {
+	if r.Kind(self) == typ.Kind() {
+		return VisBoth
+	}
+	return VisDesc
+}
+
+/*
+Implementation of `rf.Filter` that allows to visit values whose types implement
+the given interface BY POINTER. If the type is nil, this won't visit anything.
+The type must represent an interface, otherwise this will panic. The visitor
+must explicitly take value address:
+
+	func visit(val r.Value, _ r.StructField) {
+		val.Addr().Interface().(SomeInterface).SomeMethod()
+	}
+
+Receiver rules: the method set of a pointer type includes methods declared on
+both the pointer and the value, so this matches types implementing the
+interface by value, by pointer, or by a mix of both. Taking the address
+requires the walked value to be addressable, which is the case with
+`rf.WalkPtr`. To match only types which implement the interface by value, and
+call its methods without taking the address, use `rf.ValueIfaceFilter`.
+*/
+type IfaceFilter[_ any] struct{}
+
+// Implement `rf.Filter`.
+func (IfaceFilter[A]) Visit(typ r.Type, _ r.StructField) byte {
+	return ifaceVisit(typ, Type[A](), VisBoth)
+}
+
+/*
+Implementation of `rf.Filter` that allows to visit values whose types implement
+the given interface BY VALUE, meaning all methods of the interface are declared
+on the value type rather than the pointer type. It also allows to visit
+descendants. If the type is nil, this won't visit anything. The type must
+represent an interface, otherwise this will panic. Unlike with
+`rf.IfaceFilter`, the visitor doesn't need to take the value address, and the
+walked value doesn't need to be addressable:
+
+	func visit(val r.Value, _ r.StructField) {
+		val.Interface().(SomeInterface).SomeMethod()
+	}
+
+Interface types which embed the given interface, or have a superset of its
+methods, also implement it. In a walk, such interface values are visited as
+interfaces, before walking their dynamic values.
+*/
+type ValueIfaceFilter[_ any] struct{}
+
+// Implement `rf.Filter`.
+func (ValueIfaceFilter[A]) Visit(typ r.Type, _ r.StructField) byte {
+	if typ == nil {
+		return VisNone
+	}
+	if typ.Implements(Type[A]()) {
+		return VisBoth
+	}
+	return VisDesc
+}
+
+/*
+Implementation of `rf.Filter` that allows to visit values whose types are
+assignable to the given type, as defined by `reflect.Type.AssignableTo`, which
+follows the assignability rules of the language. For concrete types, this is
+generally the type itself, and unnamed types with the same underlying type,
+such as `[]byte` for a named `type Bytes []byte`. For interface types, this is
+equivalent to `rf.ValueIfaceFilter`. It also allows to visit descendants. If
+the type is nil, this won't visit anything.
+
+The visitor may assign the value to a variable of the given type without
+conversion: `val.Interface().(A)` works for interfaces; for concrete types,
+use `reflect.Value.Convert` or `reflect.Value.Set` on a value of the given
+type.
+*/
+type AssignableFilter[_ any] struct{}
+
+// Implement `rf.Filter`.
+func (AssignableFilter[A]) Visit(typ r.Type, _ r.StructField) byte {
+	if typ == nil {
+		return VisNone
+	}
+	if typ.AssignableTo(Type[A]()) {
+		return VisBoth
+	}
+	return VisDesc
+}
+
+/*
+Implementation of `rf.Filter` that allows to visit values whose types are
+convertible to the given type, as defined by `reflect.Type.ConvertibleTo`. For
+example, `rf.ConvertibleFilter[string]` matches all named types whose underlying
+type is `string`, which can be read via `reflect.Value.String` or
+`val.Convert(rf.Type[string]())`. It also allows to visit descendants. If the
+type is nil, this won't visit anything.
+
+Caution: conversion rules of the language are broader than "same underlying
+type". For example, all integer types are convertible to `string`, all numeric
+types are convertible to each other, and slices are convertible to array
+pointers of the same element type. To match only types with a specific kind,
+combine with `rf.KindFilter` via `rf.And`.
+*/
+type ConvertibleFilter[_ any] struct{}
+
+// Implement `rf.Filter`.
+func (ConvertibleFilter[A]) Visit(typ r.Type, _ r.StructField) byte {
+	if typ == nil {
+		return VisNone
+	}
+	if typ.ConvertibleTo(Type[A]()) {
+		return VisBoth
+	}
+	return VisDesc
+}
+
+/*
+Like `rf.IfaceFilter`, but visits either self or descendants, not both. In other
+words, once it finds a node that implements the given interface (by pointer),
+it allows to visit that node and stops there, without walking its descendants.
+*/
+type ShallowIfaceFilter[_ any] struct{}
+
+// Implement `rf.Filter`.
+func (ShallowIfaceFilter[A]) Visit(typ r.Type, _ r.StructField) byte {
+	return ifaceVisit(typ, Type[A](), VisSelf)
+}
+
+/*
+Implementation of `rf.Filter` that allows to visit values whose struct tag has a
+specific tag with a specific value, such as tag "json" with value "-". It also
+allows to visit descendants.
+
+Known limitation: can't differentiate empty tag from missing tag. To check for
+presence, use `rf.TagPresentFilter`. For other ways of matching tags, see
+`rf.TagIdentFilter`, `rf.TagOptFilter`, `rf.TagPrefixFilter`,
+`rf.TagGlobFilter`.
+*/
+type TagFilter [2]string
+
+// Implement `rf.Filter`.
+func (self TagFilter) Visit(_ r.Type, field r.StructField) byte {
+	key, val := self[0], self[1]
+	if key != `` && field.Tag.Get(key) == val {
+		return VisBoth
+	}
+	return VisDesc
+}
+
+/*
+Implementation of `rf.Filter` that allows to visit values whose struct field
+has the given tag, such as "json", regardless of the tag's value, including an
+empty value. It also allows to visit descendants.
+*/
+type TagPresentFilter string
+
+// Implement `rf.Filter`.
+func (self TagPresentFilter) Visit(_ r.Type, field r.StructField) byte {
+	if self != `` {
+		_, ok := field.Tag.Lookup(string(self))
+		if ok {
+			return VisBoth
+		}
+	}
+	return VisDesc
+}
+
+/*
+Implementation of `rf.Filter` that allows to visit values whose struct field
+has the given tag with the given identifier, as returned by `rf.TagIdent`. For
+example, `rf.TagIdentFilter{"json", "name"}` matches both `json:"name"` and
+`json:"name,omitempty"`. The tag must be present; an empty identifier matches
+tags such as `json:"-"` or `json:",omitempty"`, but not missing tags. It also
+allows to visit descendants.
+*/
+type TagIdentFilter [2]string
+
+// Implement `rf.Filter`.
+func (self TagIdentFilter) Visit(_ r.Type, field r.StructField) byte {
+	key, ident := self[0], self[1]
+	if key != `` {
+		tag, ok := field.Tag.Lookup(key)
+		if ok && TagIdent(tag) == ident {
+			return VisBoth
+		}
+	}
+	return VisDesc
+}
+
+/*
+Implementation of `rf.Filter` that allows to visit values whose struct field
+has the given tag with the given option, where options are the comma-separated
+parts of the tag following the identifier. For example,
+`rf.TagOptFilter{"json", "omitempty"}` matches `json:"name,omitempty"` and
+`json:",string,omitempty"`, but not `json:"omitempty"`. It also allows to visit
+descendants.
+*/
+type TagOptFilter [2]string
+
+// Implement `rf.Filter`.
+func (self TagOptFilter) Visit(_ r.Type, field r.StructField) byte {
+	key, opt := self[0], self[1]
+	if key != `` && opt != `` && tagHasOpt(field.Tag.Get(key), opt) {
+		return VisBoth
+	}
+	return VisDesc
+}
+
+/*
+Implementation of `rf.Filter` that allows to visit values whose struct field
+has the given tag with a value starting with the given prefix. The tag must be
+present; an empty prefix matches any present tag. It also allows to visit
+descendants.
+*/
+type TagPrefixFilter [2]string
+
+// Implement `rf.Filter`.
+func (self TagPrefixFilter) Visit(_ r.Type, field r.StructField) byte {
+	key, prefix := self[0], self[1]
+	if key != `` {
+		tag, ok := field.Tag.Lookup(key)
+		if ok && strings.HasPrefix(tag, prefix) {
+			return VisBoth
+		}
+	}
+	return VisDesc
+}
+
+/*
+Implementation of `rf.Filter` that allows to visit values whose struct field
+has the given tag with a value matching the given glob pattern, using the
+syntax of `path.Match`. For example, `rf.TagGlobFilter{"db", "*_id"}` matches
+`db:"user_id"`. The tag must be present. A malformed pattern matches nothing.
+It also allows to visit descendants.
+*/
+type TagGlobFilter [2]string
+
+// Implement `rf.Filter`.
+func (self TagGlobFilter) Visit(_ r.Type, field r.StructField) byte {
+	key, pattern := self[0], self[1]
+	if key != `` {
+		tag, ok := field.Tag.Lookup(key)
+		if ok {
+			match, _ := path.Match(pattern, tag)
+			if match {
+				return VisBoth
+			}
+		}
+	}
+	return VisDesc
+}
+
+/*
+Implementation of `rf.Filter` that allows to visit values of struct fields with
+the given name, such as "ID". It also allows to visit descendants.
+*/
+type FieldNameFilter string
+
+// Implement `rf.Filter`.
+func (self FieldNameFilter) Visit(_ r.Type, field r.StructField) byte {
+	if self != `` && field.Name == string(self) {
+		return VisBoth
+	}
+	return VisDesc
+}
+
+/*
+Implementation of `rf.Filter` that allows to visit values of struct fields whose
+name matches the given glob pattern, using the syntax of `path.Match`. For
+example, `rf.FieldNameGlobFilter("*At")` matches "CreatedAt" and "UpdatedAt".
+A malformed pattern matches nothing. It also allows to visit descendants.
+*/
+type FieldNameGlobFilter string
+
+// Implement `rf.Filter`.
+func (self FieldNameGlobFilter) Visit(_ r.Type, field r.StructField) byte {
+	if field.Name != `` {
+		match, _ := path.Match(string(self), field.Name)
+		if match {
+			return VisBoth
+		}
+	}
+	return VisDesc
+}
+
+/*
+Implementation of `rf.Filter` that allows to visit values of named types
+declared in the package with the given import path, as reported by
+`reflect.Type.PkgPath`. Unnamed types, such as pointers and slices, and
+predeclared types, such as "string", have no package path, and never match;
+when walking, their named element types are matched instead. It also allows to
+visit descendants. To exclude packages instead, combine with `rf.Except`.
+*/
+type PkgPathFilter string
+
+// Implement `rf.Filter`.
+func (self PkgPathFilter) Visit(typ r.Type, _ r.StructField) byte {
+	if self != `` && typ != nil && typ.PkgPath() == string(self) {
+		return VisBoth
+	}
+	return VisDesc
+}
+
+/*
+Like `rf.PkgPathFilter`, but matches package paths by pattern. The pattern uses
+the syntax of `path.Match`, where "*" doesn't match "/". Additionally, like in
+Go tooling, a pattern ending with "/..." matches the preceding path and any
+path nested under it, and the pattern "..." matches any package path. Example
+of excluding all packages from a vendor:
+
+	rf.And{filter, rf.Except{rf.PkgPathGlobFilter("github.com/vendor/...")}}
+
+A malformed pattern matches nothing.
+*/
+type PkgPathGlobFilter string
+
+// Implement `rf.Filter`.
+func (self PkgPathGlobFilter) Visit(typ r.Type, _ r.StructField) byte {
+	if typ != nil && pkgPathMatch(string(self), typ.PkgPath()) {
+		return VisBoth
+	}
+	return VisDesc
+}
+
+/*
+Implementation of `rf.Filter` intended for excluding nodes from other filters
+via `rf.And`. Returns `rf.VisNone` for nodes which the inner filter allows to
+visit (with `rf.VisSelf`), excluding them together with their descendants, and
+`rf.VisAll` for other nodes, leaving them to other filters. If the inner filter
+is nil, this always returns `rf.VisAll`.
+Examples:
+
+	rf.And{filter, rf.Except{rf.FieldNameFilter("Password")}}
+	rf.And{filter, rf.Except{rf.PkgPathFilter("github.com/some/pkg")}}
+
+Unlike `rf.Not`, this doesn't turn non-matching nodes into visited nodes.
+*/
+type Except [1]Filter
+
+// Implement `rf.Filter`.
+func (self Except) Visit(typ r.Type, field r.StructField) byte {
+	return self.visit(typ, field, -1)
+}
+
+// Implement `rf.DepthFilter`.
+func (self Except) VisitDepth(typ r.Type, field r.StructField, depth int) byte {
+	return self.visit(typ, field, depth)
+}
+
+func (self Except) usesDepth() bool { return usesDepth(self[0]) }
+
+func (self Except) visit(typ r.Type, field r.StructField, depth int) byte {
+	if self[0] != nil && vis(visitDepth(self[0], typ, field, depth)).self() {
+		return VisNone
+	}
+	return VisAll
+}
+
+// Implement `rf.ValueFilter`.
+func (self Except) VisitValue(val r.Value, field r.StructField) byte {
+	return self.visitValue(val, field, -1)
+}
+
+func (self Except) usesValue() bool { return usesValue(self[0]) }
+
+func (self Except) visitValue(val r.Value, field r.StructField, depth int) byte {
+	if self[0] != nil && vis(visitValue(self[0], val, field, depth)).self() {
+		return VisNone
+	}
+	return VisAll
+}
+
+/*
+Implementation of `rf.Filter` that inverts the "self" bit of the inner filter,
+without changing the other flags. If the inner filter is nil, this always
+returns `rf.VisNone`. Implements `rf.PrivateFilter` by delegating to the inner
+filter.
+*/
+type InvertSelf [1]Filter
+
+// Implement `rf.Filter`.
+func (self InvertSelf) Visit(typ r.Type, field r.StructField) byte {
+	return self.visit(typ, field, -1)
+}
+
+// Implement `rf.DepthFilter`.
+func (self InvertSelf) VisitDepth(typ r.Type, field r.StructField, depth int) byte {
+	return self.visit(typ, field, depth)
+}
+
+func (self InvertSelf) usesDepth() bool { return usesDepth(self[0]) }
+
+func (self InvertSelf) visit(typ r.Type, field r.StructField, depth int) byte {
+	if self[0] == nil {
+		return VisNone
+	}
+	return visitDepth(self[0], typ, field, depth) ^ VisSelf
+}
+
+// Implement `rf.ValueFilter`.
+func (self InvertSelf) VisitValue(val r.Value, field r.StructField) byte {
+	return self.visitValue(val, field, -1)
+}
+
+func (self InvertSelf) usesValue() bool { return usesValue(self[0]) }
+
+func (self InvertSelf) visitValue(val r.Value, field r.StructField, depth int) byte {
+	if self[0] == nil {
+		return VisNone
+	}
+	return visitValue(self[0], val, field, depth) ^ VisSelf
+}
+
+// Implement `rf.PrivateFilter`.
+func (self InvertSelf) VisitPrivate(typ r.Type, field r.StructField) bool {
+	return visitPrivate(self[0], typ, field)
+}
+
+// Implement `rf.MapFilter`.
+func (self InvertSelf) VisitMap(typ r.Type, field r.StructField) byte {
+	return visitMap(self[0], typ, field)
+}
+
+/*
+Optional extension of `rf.Filter` for filters which depend on the depth of the
+node in the walked structure. During walker generation, walkers call
+`.VisitDepth` instead of `.Visit`, providing the depth of each node. The root
+has depth 0. Each struct field, list element, map key and map value is one
+level deeper than its parent. Pointers and interfaces don't add levels: the
+target of a pointer has the same depth as the pointer. In `rf.WalkFlat`,
+fields of value-embedded structs are one level deeper than the enclosing
+struct, like other fields.
+
+All filter combinators in this package, such as `rf.And` and `rf.Or`, implement
+this interface, forwarding the depth to inner filters.
+
+Caution: for depth-aware filters, walkers can't reuse the walkers of outer
+occurrences of cyclic types, because the filter may return different results
+at different depths. A depth-aware filter must eventually stop descending
+into cyclic types, for example via `rf.MaxDepth`. Generating walkers for a
+filter which descends more than 1024 levels deep panics with a descriptive
+error. This also applies to `rf.Diff` and `rf.CloneWith`.
+*/
+type DepthFilter interface {
+	Filter
+	VisitDepth(typ r.Type, field r.StructField, depth int) byte
+}
+
+/*
+Optional extension of `rf.Filter` for filters which depend on walked values,
+rather than only on types. The static method `.Visit` is used as usual during
+walker generation, and determines what may be visited. Then, during each walk,
+walkers call `.VisitValue` for each node allowed by `.Visit`, providing the
+value of that node. Its result is intersected with the static result: it may
+prevent visiting the node and/or walking its descendants, but can't enable
+what `.Visit` didn't. Only `rf.VisSelf` and `rf.VisDesc` are considered;
+map walking is determined statically. The field is the same as for `.Visit`.
+
+All filter combinators in this package, such as `rf.And` and `rf.Or`, implement
+this interface, combining the results of their inner filters like for `.Visit`.
+Inner filters which don't implement this interface contribute their static
+results. Walkers call `.VisitValue` on combinators only when at least one inner
+filter implements this interface. Because the results can only narrow the
+static results, inverting combinators such as `rf.Not` can't use value filters
+to visit more nodes. Example:
+
+	type ActiveFilter struct{}
+
+	func (ActiveFilter) Visit(typ r.Type, _ r.StructField) byte {
+		if typ == rf.Type[Account]() {
+			return rf.VisBoth
+		}
+		return rf.VisDesc
+	}
+
+	func (ActiveFilter) VisitValue(val r.Value, _ r.StructField) byte {
+		if val.Type() == rf.Type[Account]() && !val.Interface().(Account).Active {
+			return rf.VisNone
+		}
+		return rf.VisBoth
+	}
+
+Walkers for filters which don't implement this interface are unaffected. For
+filters which do implement it, the method is called once per walked node, and
+should be cheap.
+*/
+type ValueFilter interface {
+	Filter
+	VisitValue(val r.Value, field r.StructField) byte
+}
+
+/*
+Implementation of `rf.Filter` and `rf.DepthFilter` that delegates to the inner
+filter for nodes up to the given depth, inclusive, and doesn't descend below
+it. See `rf.DepthFilter` for the definition of depth. Pointers and interfaces
+at the maximum depth are still dereferenced, because their targets have the
+same depth. If the inner filter is nil or the depth is negative, this always
+returns `rf.VisNone`. When used outside of walking, `.Visit` delegates to the
+inner filter without limits. Example:
+
+	// Visits strings in the top-level struct and its direct children.
+	rf.MaxDepth{rf.TypeFilter[string]{}, 2}
+
+Implements `rf.PrivateFilter` by delegating to the inner filter.
+*/
+type MaxDepth struct {
+	Filter Filter
+	Depth  int
+}
+
+// Implement `rf.Filter`.
+func (self MaxDepth) Visit(typ r.Type, field r.StructField) byte {
+	if self.Filter == nil {
+		return VisNone
+	}
+	return self.Filter.Visit(typ, field)
+}
+
+// Implement `rf.DepthFilter`.
+func (self MaxDepth) VisitDepth(typ r.Type, field r.StructField, depth int) byte {
+	if self.Filter == nil || depth > self.Depth {
+		return VisNone
+	}
+
+	return self.limit(typ, depth, visitDepth(self.Filter, typ, field, depth))
+}
+
+// Implement `rf.ValueFilter`.
+func (self MaxDepth) VisitValue(val r.Value, field r.StructField) byte {
+	return self.visitValue(val, field, -1)
+}
+
+func (self MaxDepth) usesValue() bool { return usesValue(self.Filter) }
+
+func (self MaxDepth) visitValue(val r.Value, field r.StructField, depth int) byte {
+	if self.Filter == nil {
+		return VisNone
+	}
+	if depth < 0 {
+		return visitValue(self.Filter, val, field, depth)
+	}
+	if depth > self.Depth {
+		return VisNone
+	}
+	return self.limit(val.Type(), depth, visitValue(self.Filter, val, field, depth))
+}
+
+func (self MaxDepth) limit(typ r.Type, depth int, out byte) byte {
+	if depth == self.Depth && !isKindDeref(typ) {
+		out &^= VisDesc
+	}
+	return out
+}
+
+// Implement `rf.PrivateFilter`.
+func (self MaxDepth) VisitPrivate(typ r.Type, field r.StructField) bool {
+	return visitPrivate(self.Filter, typ, field)
+}
+
+// Implement `rf.MapFilter`.
+func (self MaxDepth) VisitMap(typ r.Type, field r.StructField) byte {
+	return visitMap(self.Filter, typ, field)
+}
+
+func (MaxDepth) usesDepth() bool { return true }
+
+/*
+Optional extension of `rf.Filter` that allows walking to include private
+(unexported) struct fields, which are excluded by default. During walker
+generation, `.VisitPrivate` is called for each private field, with the field's
+type and the field. If it returns true, the field is included, and then
+`.Visit` is called for it as usual.
+
+Private fields are normally read-only in "reflect": `reflect.Value.Interface`
+and `reflect.Value.Set` panic on them. Walkers bypass this restriction via
+`reflect.NewAt` and "unsafe", providing visitors with values of private fields
+that can be read and, when the walked value is addressable (see `rf.WalkPtr`),
+modified. When the walked value is not addressable, walking a private field
+makes a copy of the enclosing struct; modifying the field doesn't modify the
+original.
+
+The combinators `rf.And`, `rf.Or`, `rf.AndList`, `rf.OrList`, `rf.Xor`,
+`rf.Not`, `rf.InvertSelf` implement this interface, including private fields if
+any of their inner filters does. For a simple way to include all private fields,
+see `rf.AllowPrivate`.
+*/
+type PrivateFilter interface {
+	Filter
+	VisitPrivate(r.Type, r.StructField) bool
+}
+
+/*
+Implementation of `rf.Filter` and `rf.PrivateFilter` that delegates to the inner
+filter, while allowing to walk all private fields. If the inner filter is nil,
+this always returns `rf.VisNone`. Example:
+
+	rf.WalkPtr(&val, rf.AllowPrivate{rf.TypeFilter[*sql.DB]{}}, visitor)
+*/
+type AllowPrivate [1]Filter
+
+// Implement `rf.Filter`.
+func (self AllowPrivate) Visit(typ r.Type, field r.StructField) byte {
+	return self.visit(typ, field, -1)
+}
+
+// Implement `rf.DepthFilter`.
+func (self AllowPrivate) VisitDepth(typ r.Type, field r.StructField, depth int) byte {
+	return self.visit(typ, field, depth)
+}
+
+func (self AllowPrivate) usesDepth() bool { return usesDepth(self[0]) }
+
+func (self AllowPrivate) visit(typ r.Type, field r.StructField, depth int) byte {
+	if self[0] == nil {
+		return VisNone
+	}
+	return visitDepth(self[0], typ, field, depth)
+}
+
+// Implement `rf.ValueFilter`.
+func (self AllowPrivate) VisitValue(val r.Value, field r.StructField) byte {
+	return self.visitValue(val, field, -1)
+}
+
+func (self AllowPrivate) usesValue() bool { return usesValue(self[0]) }
+
+func (self AllowPrivate) visitValue(val r.Value, field r.StructField, depth int) byte {
+	if self[0] == nil {
+		return VisNone
+	}
+	return visitValue(self[0], val, field, depth)
+}
+
+// Implement `rf.PrivateFilter`.
+func (AllowPrivate) VisitPrivate(r.Type, r.StructField) bool { return true }
+
+// Implement `rf.MapFilter`.
+func (self AllowPrivate) VisitMap(typ r.Type, field r.StructField) byte {
+	return visitMap(self[0], typ, field)
+}
+
+/*
+Micro-optimization for `rf.And`. If the input has NO non-nil filters, returns
+nil. If the input has ONE non-nil filter, returns that filter, avoiding an
+allocation of `rf.And{}`. Otherwise combines the filters via `rf.And`, or via
+`rf.AndOf` if there are too many filters for `rf.And`.
+*/
+func MaybeAnd(vals ...Filter) Filter {
+	var out And
+	slice, ok := maybeCombineFilters(vals, out[:0])
+	if !ok {
+		return AndOf(vals...)
+	}
+
+	switch len(slice) {
+	case 0:
+		return nil
+	case 1:
+		return slice[0]
+	default:
+		return out
+	}
+}
+
+/*
+Implementation of `rf.Filter` that combines other filters, AND-ing their outputs
+via `&`. Nil elements are ignored. If all elements are nil, the output is
+automatically `VisNone`. Limited to 8 filters; for more, see `rf.AndOf`.
+*/
+type And [8]Filter
+
+// Implement `rf.Filter`.
+func (self And) Visit(typ r.Type, field r.StructField) byte {
+	return self.visit(typ, field, -1)
+}
+
+// Implement `rf.DepthFilter`.
+func (self And) VisitDepth(typ r.Type, field r.StructField, depth int) byte {
+	return self.visit(typ, field, depth)
+}
+
+func (self And) usesDepth() bool { return usesDepthAny(self[:]) }
+
+func (self And) visit(typ r.Type, field r.StructField, depth int) (vis byte) {
+	var found bool
+
+	for _, val := range self {
+		if val != nil {
+			if !found {
+				found = true
+				vis = visitDepth(val, typ, field, depth)
+			} else {
+				vis &= visitDepth(val, typ, field, depth)
+			}
+		}
+	}
+
+	return
+}
+
+// Implement `rf.ValueFilter`.
+func (self And) VisitValue(val r.Value, field r.StructField) byte {
+	return self.visitValue(val, field, -1)
+}
+
+func (self And) usesValue() bool { return usesValueAny(self[:]) }
+
+func (self And) visitValue(val r.Value, field r.StructField, depth int) (vis byte) {
+	var found bool
+
+	for _, fil := range self {
+		if fil != nil {
+			if !found {
+				found = true
+				vis = visitValue(fil, val, field, depth)
+			} else {
+				vis &= visitValue(fil, val, field, depth)
+			}
+		}
+	}
+
+	return
+}
+
+// Implement `rf.PrivateFilter`.
+func (self And) VisitPrivate(typ r.Type, field r.StructField) bool {
+	return visitPrivateAny(self[:], typ, field)
+}
+
+// Implement `rf.MapFilter`.
+func (self And) VisitMap(typ r.Type, field r.StructField) byte {
+	return visitMapAny(self[:], typ, field)
+}
+
+/*
+Micro-optimization for `rf.Or`. If the input has NO non-nil filters, returns
+nil. If the input has ONE non-nil filter, returns that filter, avoiding an
+allocation of `rf.Or{}`. Otherwise combines the filters via `rf.Or`, or via
+`rf.OrOf` if there are too many filters for `rf.Or`.
+*/
+func MaybeOr(vals ...Filter) Filter {
+	var out Or
+	slice, ok := maybeCombineFilters(vals, out[:0])
+	if !ok {
+		return OrOf(vals...)
+	}
+
+	switch len(slice) {
+	case 0:
+		return nil
+	case 1:
+		return slice[0]
+	default:
+		return out
+	}
+}
+
+/*
+Implementation of `rf.Filter` that combines other filters, OR-ing their outputs
+via `|`. Nil elements are ignored. If all elements are nil, the output is
+automatically `VisNone`. Limited to 8 filters; for more, see `rf.OrOf`.
+*/
+type Or [8]Filter
+
+// Implement `rf.Filter`.
+func (self Or) Visit(typ r.Type, field r.StructField) byte {
+	return self.visit(typ, field, -1)
+}
+
+// Implement `rf.DepthFilter`.
+func (self Or) VisitDepth(typ r.Type, field r.StructField, depth int) byte {
+	return self.visit(typ, field, depth)
+}
+
+func (self Or) usesDepth() bool { return usesDepthAny(self[:]) }
+
+func (self Or) visit(typ r.Type, field r.StructField, depth int) (vis byte) {
+	for _, val := range self {
+		if val != nil {
+			vis |= visitDepth(val, typ, field, depth)
+		}
+	}
+	return
+}
+
+// Implement `rf.ValueFilter`.
+func (self Or) VisitValue(val r.Value, field r.StructField) byte {
+	return self.visitValue(val, field, -1)
+}
+
+func (self Or) usesValue() bool { return usesValueAny(self[:]) }
+
+func (self Or) visitValue(val r.Value, field r.StructField, depth int) (vis byte) {
+	for _, fil := range self {
+		if fil != nil {
+			vis |= visitValue(fil, val, field, depth)
+		}
+	}
+	return
+}
+
+// Implement `rf.PrivateFilter`.
+func (self Or) VisitPrivate(typ r.Type, field r.StructField) bool {
+	return visitPrivateAny(self[:], typ, field)
+}
+
+// Implement `rf.MapFilter`.
+func (self Or) VisitMap(typ r.Type, field r.StructField) byte {
+	return visitMapAny(self[:], typ, field)
+}
+
+/*
+Returns a filter equivalent to `rf.And` with the given filters, without a limit
+on their count. Nil elements are ignored. The filters are interned via the
+standard package "unique", and the returned value only holds a handle to
+their list. The handle is comparable and small, which allows to use it in
+walker cache keys, and equal lists of filters produce equal handles. Interning
+costs one allocation per filter, so this is intended for filter lists built
+once, for example from configuration, rather than for each walk. Lists which
+are no longer referenced, including by cached walkers, are reclaimed by the
+runtime. Panics if any of the filters is invalid (see `rf.Filter`).
+*/
+func AndOf(vals ...Filter) AndList { return AndList{internFilters(vals)} }
+
+/*
+Implementation of `rf.Filter` that combines an unlimited count of other filters,
+AND-ing their outputs via `&`. Must be created via `rf.AndOf`. The zero value
+has no filters, and always returns `rf.VisNone`.
+*/
+type AndList struct{ list filterList }
+
+// Implement `rf.Filter`.
+func (self AndList) Visit(typ r.Type, field r.StructField) byte {
+	return self.visit(typ, field, -1)
+}
+
+// Implement `rf.DepthFilter`.
+func (self AndList) VisitDepth(typ r.Type, field r.StructField, depth int) byte {
+	return self.visit(typ, field, depth)
+}
+
+func (self AndList) usesDepth() bool { return self.list.usesDepth() }
+
+func (self AndList) visit(typ r.Type, field r.StructField, depth int) (vis byte) {
+	var found bool
+
+	for val := range self.list.all {
+		if !found {
+			found = true
+			vis = visitDepth(val, typ, field, depth)
+		} else {
+			vis &= visitDepth(val, typ, field, depth)
+		}
+	}
+
+	return
+}
+
+// Implement `rf.ValueFilter`.
+func (self AndList) VisitValue(val r.Value, field r.StructField) byte {
+	return self.visitValue(val, field, -1)
+}
+
+func (self AndList) usesValue() bool { return self.list.usesValue() }
+
+func (self AndList) visitValue(val r.Value, field r.StructField, depth int) (vis byte) {
+	var found bool
+
+	for fil := range self.list.all {
+		if !found {
+			found = true
+			vis = visitValue(fil, val, field, depth)
+		} else {
+			vis &= visitValue(fil, val, field, depth)
+		}
+	}
+
+	return
+}
+
+// Implement `rf.PrivateFilter`.
+func (self AndList) VisitPrivate(typ r.Type, field r.StructField) bool {
+	return self.list.visitPrivate(typ, field)
+}
+
+// Implement `rf.MapFilter`.
+func (self AndList) VisitMap(typ r.Type, field r.StructField) byte {
+	return self.list.visitMap(typ, field)
+}
+
+// Returns a new slice of the non-nil filters given to `rf.AndOf`.
+func (self AndList) Filters() []Filter { return self.list.slice() }
+
+// Like `rf.AndOf`, but for `rf.Or`.
+func OrOf(vals ...Filter) OrList { return OrList{internFilters(vals)} }
+
+/*
+Implementation of `rf.Filter` that combines an unlimited count of other filters,
+OR-ing their outputs via `|`. Must be created via `rf.OrOf`. The zero value has
+no filters, and always returns `rf.VisNone`.
+*/
+type OrList struct{ list filterList }
+
+// Implement `rf.Filter`.
+func (self OrList) Visit(typ r.Type, field r.StructField) byte {
+	return self.visit(typ, field, -1)
+}
+
+// Implement `rf.DepthFilter`.
+func (self OrList) VisitDepth(typ r.Type, field r.StructField, depth int) byte {
+	return self.visit(typ, field, depth)
+}
+
+func (self OrList) usesDepth() bool { return self.list.usesDepth() }
+
+func (self OrList) visit(typ r.Type, field r.StructField, depth int) (vis byte) {
+	for val := range self.list.all {
+		vis |= visitDepth(val, typ, field, depth)
+	}
+	return
+}
+
+// Implement `rf.ValueFilter`.
+func (self OrList) VisitValue(val r.Value, field r.StructField) byte {
+	return self.visitValue(val, field, -1)
+}
+
+func (self OrList) usesValue() bool { return self.list.usesValue() }
+
+func (self OrList) visitValue(val r.Value, field r.StructField, depth int) (vis byte) {
+	for fil := range self.list.all {
+		vis |= visitValue(fil, val, field, depth)
+	}
+	return
+}
+
+// Implement `rf.PrivateFilter`.
+func (self OrList) VisitPrivate(typ r.Type, field r.StructField) bool {
+	return self.list.visitPrivate(typ, field)
+}
+
+// Implement `rf.MapFilter`.
+func (self OrList) VisitMap(typ r.Type, field r.StructField) byte {
+	return self.list.visitMap(typ, field)
+}
+
+// Returns a new slice of the non-nil filters given to `rf.OrOf`.
+func (self OrList) Filters() []Filter { return self.list.slice() }
+
+/*
+Implementation of `rf.Filter` that inverts both `rf.VisSelf` and `rf.VisDesc`
+of the inner filter, without changing the other flags. If the inner filter is
+nil, this always returns `rf.VisNone`. See also `rf.InvertSelf`. Implements
+`rf.PrivateFilter` by delegating to the inner filter.
+*/
+type Not [1]Filter
+
+// Implement `rf.Filter`.
+func (self Not) Visit(typ r.Type, field r.StructField) byte {
+	return self.visit(typ, field, -1)
+}
+
+// Implement `rf.DepthFilter`.
+func (self Not) VisitDepth(typ r.Type, field r.StructField, depth int) byte {
+	return self.visit(typ, field, depth)
+}
+
+func (self Not) usesDepth() bool { return usesDepth(self[0]) }
+
+func (self Not) visit(typ r.Type, field r.StructField, depth int) byte {
+	if self[0] == nil {
+		return VisNone
+	}
+	return visitDepth(self[0], typ, field, depth) ^ VisBoth
+}
+
+// Implement `rf.ValueFilter`.
+func (self Not) VisitValue(val r.Value, field r.StructField) byte {
+	return self.visitValue(val, field, -1)
+}
+
+func (self Not) usesValue() bool { return usesValue(self[0]) }
+
+func (self Not) visitValue(val r.Value, field r.StructField, depth int) byte {
+	if self[0] == nil {
+		return VisNone
+	}
+	return visitValue(self[0], val, field, depth) ^ VisBoth
+}
+
+// Implement `rf.PrivateFilter`.
+func (self Not) VisitPrivate(typ r.Type, field r.StructField) bool {
+	return visitPrivate(self[0], typ, field)
+}
+
+// Implement `rf.MapFilter`.
+func (self Not) VisitMap(typ r.Type, field r.StructField) byte {
+	return visitMap(self[0], typ, field)
+}
+
+/*
+Implementation of `rf.Filter` that combines two filters, XOR-ing their outputs
+via `^`. Nil elements are treated as returning `rf.VisNone`. Implements
+`rf.PrivateFilter`, including private fields if either inner filter does.
+*/
+type Xor [2]Filter
+
+// Implement `rf.Filter`.
+func (self Xor) Visit(typ r.Type, field r.StructField) byte {
+	return self.visit(typ, field, -1)
+}
+
+// Implement `rf.DepthFilter`.
+func (self Xor) VisitDepth(typ r.Type, field r.StructField, depth int) byte {
+	return self.visit(typ, field, depth)
+}
+
+func (self Xor) usesDepth() bool { return usesDepthAny(self[:]) }
+
+func (self Xor) visit(typ r.Type, field r.StructField, depth int) (vis byte) {
+	for _, val := range self {
+		if val != nil {
+			vis ^= visitDepth(val, typ, field, depth)
+		}
+	}
+	return
+}
+
+// Implement `rf.ValueFilter`.
+func (self Xor) VisitValue(val r.Value, field r.StructField) byte {
+	return self.visitValue(val, field, -1)
+}
+
+func (self Xor) usesValue() bool { return usesValueAny(self[:]) }
+
+func (self Xor) visitValue(val r.Value, field r.StructField, depth int) (vis byte) {
+	for _, fil := range self {
+		if fil != nil {
+			vis ^= visitValue(fil, val, field, depth)
+		}
+	}
+	return
+}
+
+// Implement `rf.PrivateFilter`.
+func (self Xor) VisitPrivate(typ r.Type, field r.StructField) bool {
+	return visitPrivateAny(self[:], typ, field)
+}
+
+// Implement `rf.MapFilter`.
+func (self Xor) VisitMap(typ r.Type, field r.StructField) byte {
+	return visitMapAny(self[:], typ, field)
+}
+
+// No-op implementation of both `rf.Visitor` that does nothing upon visit.
+type Nop struct{}
+
+// Implement `rf.Visitor`.
+func (Nop) Visit(r.Value, r.StructField) {}
+
+// Implements `rf.Visitor` by appending visited non-zero elements.
+type Appender[A any] []A
+
+/*
+Implement `rf.Visitor` by appending the input value to the inner slice, if the
+value is non-zero.
+*/
+func (self *Appender[A]) Visit(val r.Value, _ r.StructField) {
+	if self != nil && !val.IsZero() {
+		*self = append(*self, valueOf[A](val))
+	}
+}
+
+/*
+Returns a filter that allows to visit only values suitable to be elements of the
+slice held by the appender.
 */
 func (self Appender[A]) Filter() Filter { return TypeFilter[A]{} }
+
+/*
+Variant of `rf.Appender` which appends only non-zero elements not yet present
+in the inner slice, comparing them via `==`. The set of known elements is
+created lazily from the current elements of the inner slice. Just like with
+map keys, comparing interface values with uncomparable dynamic types panics.
+*/
+type UniqueAppender[A comparable] struct {
+	Appender[A]
+	Set map[A]struct{}
+}
+
+/*
+Implement `rf.Visitor` by appending the input value to the inner slice, if the
+value is non-zero and not yet present.
+*/
+func (self *UniqueAppender[A]) Visit(val r.Value, _ r.StructField) {
+	if self == nil || val.IsZero() {
+		return
+	}
+
+	if self.Set == nil {
+		self.Set = make(map[A]struct{}, len(self.Appender))
+		for _, val := range self.Appender {
+			self.Set[val] = struct{}{}
+		}
+	}
+
+	tar := valueOf[A](val)
+	if _, ok := self.Set[tar]; ok {
+		return
+	}
+	self.Set[tar] = struct{}{}
+	self.Appender = append(self.Appender, tar)
+}
+
+/*
+Implements `rf.Visitor` by appending pointers to visited elements, including
+zero elements. Visited elements must be addressable; elements which are not,
+such as those stored directly in interfaces or maps, are skipped. Walk a
+pointer to make the walked value addressable.
+*/
+type PtrAppender[A any] []*A
+
+// Implement `rf.Visitor` by appending a pointer to the input value.
+func (self *PtrAppender[A]) Visit(val r.Value, _ r.StructField) {
+	if self != nil && val.CanAddr() {
+		*self = append(*self, val.Addr().Interface().(*A))
+	}
+}
+
+/*
+Returns a filter that allows to visit only values whose pointers are suitable
+to be elements of the slice held by the appender.
+*/
+func (self PtrAppender[A]) Filter() Filter { return TypeFilter[A]{} }
+
+/*
+Concurrency-safe counterpart of `rf.Appender`, suitable for `rf.WalkParallel`.
+Implements `rf.Visitor` by appending visited non-zero elements to the inner
+`rf.Appender` while holding the lock. The inner appender should be accessed
+directly only when not walking.
+*/
+type SyncAppender[A any] struct {
+	sync.Mutex
+	Appender[A]
+}
+
+// Implement `rf.Visitor` by calling `rf.Appender.Visit` under lock.
+func (self *SyncAppender[A]) Visit(val r.Value, field r.StructField) {
+	if self == nil {
+		return
+	}
+	self.Lock()
+	defer self.Unlock()
+	self.Appender.Visit(val, field)
+}
+
+// Implement `rf.ConcurrentVisitor`.
+func (*SyncAppender[_]) IsConcurrent() bool { return true }
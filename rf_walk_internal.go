@@ -2,14 +2,20 @@ package rf
 
 import (
 	"fmt"
+	"path"
 	r "reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"unique"
+	u "unsafe"
 )
 
 var (
-	walkerCacheStatic walkerCache
+	walkerCacheStatic WalkerCache
 	typeFilter        = r.TypeOf((*Filter)(nil)).Elem()
 	typeType          = r.TypeOf((*r.Type)(nil)).Elem()
+	typeFilterList    = r.TypeOf(filterList{})
 )
 
 /**
@@ -22,46 +28,257 @@ type vis byte
 
 func (self vis) self() bool { return (self & VisSelf) != 0 }
 func (self vis) desc() bool { return (self & VisDesc) != 0 }
-
-type walkerCache struct {
-	sync.RWMutex
-	Map map[walkRef]Walker
+func (self vis) key() bool  { return (self & VisKey) != 0 }
+func (self vis) val() bool  { return (self & VisVal) != 0 }
+
+// Implement `fmt.Stringer`. Used by `rf.Explain`.
+func (self vis) String() string {
+	var buf []string
+	if self.self() {
+		buf = append(buf, `self`)
+	}
+	if self.desc() {
+		buf = append(buf, `desc`)
+	}
+	if self.key() {
+		buf = append(buf, `key`)
+	}
+	if self.val() {
+		buf = append(buf, `val`)
+	}
+	if len(buf) == 0 {
+		return `none`
+	}
+	return strings.Join(buf, `|`)
 }
 
-func (self *walkerCache) getOrMake(typ r.Type, fil Filter) Walker {
+func (self *WalkerCache) getWalker(typ r.Type, fil Filter, mode walkMode) Walker {
+	if typ == nil || fil == nil {
+		return nil
+	}
+
 	var ref walkRef
 	ref.Filter = fil
+	ref.Mode = mode
 	ref.Type = typ
 	return self.getOrMakeFor(ref)
 }
 
-func (self *walkerCache) getOrMakeFor(ref walkRef) Walker {
+func (self *WalkerCache) getOrMakeFor(ref walkRef) Walker {
+	return self.walkers.get(ref, self.Cap, self.makeWalker)
+}
+
+func (self *WalkerCache) makeWalker(ref walkRef) Walker {
+	return ref.makeWalker(self)
+}
+
+/**
+Cache of values generated for each `walkRef`, such as walkers. Used by
+`rf.WalkerCache`, which keeps a separate cache for each kind of value.
+
+Steady-state lookups read an immutable map snapshot via `sync/atomic`, without
+locking. On a miss, the first caller builds the value, while concurrent callers
+for the same key wait for that build instead of duplicating it. Builds for
+different keys run concurrently. The mutex is held only for book keeping, never
+during a build.
+*/
+type refCache[A any] struct {
+	dict      atomic.Value // map[walkRef]A
+	lock      sync.Mutex
+	pending   map[walkRef]*refBuild[A]
+	builds    int
+	evictions int
+}
+
+func (self *refCache[A]) get(ref walkRef, limit int, fun func(walkRef) A) A {
 	val, ok := self.got(ref)
 	if ok {
 		return val
 	}
-	ref.validate()
-	return self.set(ref, ref.makeWalker())
+	return self.build(ref, limit, fun)
 }
 
 // The boolean is not redundant: we generate nil walkers for some keys.
-func (self *walkerCache) got(ref walkRef) (Walker, bool) {
-	self.RLock()
-	defer self.RUnlock()
-	val, ok := self.Map[ref]
+func (self *refCache[A]) got(ref walkRef) (A, bool) {
+	val, ok := self.load()[ref]
 	return val, ok
 }
 
-func (self *walkerCache) set(ref walkRef, val Walker) Walker {
-	self.Lock()
-	defer self.Unlock()
+func (self *refCache[A]) load() map[walkRef]A {
+	val, _ := self.dict.Load().(map[walkRef]A)
+	return val
+}
+
+func (self *refCache[A]) len() int { return len(self.load()) }
+
+func (self *refCache[A]) build(ref walkRef, limit int, fun func(walkRef) A) A {
+	self.lock.Lock()
+
+	// Another goroutine may have finished building between our lookup and
+	// acquiring the lock.
+	val, ok := self.got(ref)
+	if ok {
+		self.lock.Unlock()
+		return val
+	}
+
+	pending := self.pending[ref]
+	if pending != nil {
+		self.lock.Unlock()
+		return pending.wait()
+	}
+
+	pending = new(refBuild[A])
+	pending.Add(1)
+	if self.pending == nil {
+		self.pending = map[walkRef]*refBuild[A]{}
+	}
+	self.pending[ref] = pending
+	self.lock.Unlock()
+
+	self.make(ref, limit, fun, pending)
+	return pending.wait()
+}
+
+func (self *refCache[A]) make(ref walkRef, limit int, fun func(walkRef) A, pending *refBuild[A]) {
+	defer self.done(ref, limit, pending)
+	defer pending.Panic.catch()
+
+	ref.validate()
+	pending.Val = fun(ref)
+	pending.Ok = true
+}
+
+/**
+Stores the value and releases the waiters. Panics during the build are stored
+in the pending build and rethrown in every waiter, but the value is not cached,
+and the next caller tries again.
+*/
+func (self *refCache[A]) done(ref walkRef, limit int, pending *refBuild[A]) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	defer pending.Done()
+
+	delete(self.pending, ref)
+	if pending.Ok {
+		self.store(ref, limit, pending.Val)
+	}
+}
+
+/**
+Must be called under the lock. Copies the current map, because the current
+snapshot may be in use by concurrent readers. This makes each insertion linear
+in the size of the cache, which is fine because insertions happen once per
+type+filter combination, while lookups happen on every walk.
+*/
+func (self *refCache[A]) store(ref walkRef, limit int, val A) {
+	prev := self.load()
+	next := make(map[walkRef]A, len(prev)+1)
+	for key, val := range prev {
+		next[key] = val
+	}
+
+	self.evict(next, limit)
+	next[ref] = val
+	self.builds++
+	self.dict.Store(next)
+}
+
+/**
+Must be called under the lock, on a map not yet visible to readers, before
+adding an entry. Relies on the randomized iteration order of Go maps, which
+makes this equivalent to random eviction. Random eviction requires no book
+keeping on lookups, which keeps lookups read-only.
+*/
+func (self *refCache[A]) evict(tar map[walkRef]A, limit int) {
+	for key := range tar {
+		if limit <= 0 || len(tar) < limit {
+			return
+		}
+		delete(tar, key)
+		self.evictions++
+	}
+}
+
+func (self *refCache[A]) clear() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.dict.Store(map[walkRef]A(nil))
+}
+
+func (self *refCache[A]) delete(typ r.Type) (count int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	prev := self.load()
+	next := make(map[walkRef]A, len(prev))
+
+	for key, val := range prev {
+		if key.Type == typ {
+			count++
+		} else {
+			next[key] = val
+		}
+	}
+
+	if count > 0 {
+		self.dict.Store(next)
+	}
+	return
+}
+
+// Adds the stats of this cache to the output.
+func (self *refCache[A]) stats(out *WalkerCacheStats) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	out.Len += self.len()
+	out.Builds += self.builds
+	out.Evictions += self.evictions
+}
+
+/**
+Build of a single value, shared by concurrent callers requesting the same
+value, including the building goroutine. Fields other than the wait group are
+written by the building goroutine, and read by others only after `.Wait`.
+*/
+type refBuild[A any] struct {
+	sync.WaitGroup
+	Val   A
+	Ok    bool
+	Panic parPanic
+}
+
+func (self *refBuild[A]) wait() A {
+	self.Wait()
+	self.Panic.rethrow()
+	return self.Val
+}
+
+func (self *WalkerCache) walkWithMode(val r.Value, fil Filter, vis Visitor, mode walkMode) {
+	self.walkWithState(val, fil, mode, walkState{Vis: vis})
+}
+
+// The state must have a visitor in `.Vis`. See `walkWithMode`.
+func (self *WalkerCache) walkWithState(val r.Value, fil Filter, mode walkMode, state walkState) {
+	if state.Vis == nil {
+		return
+	}
+
+	state.LeaveVis, _ = state.Vis.(LeaveVisitor)
+	if state.LeaveVis != nil {
+		mode |= walkModeLeave
+	}
+
+	wal := self.getWalker(ValueType(val), fil, mode)
+	if wal == nil {
+		return
+	}
 
-	if self.Map == nil {
-		self.Map = map[walkRef]Walker{ref: val}
+	if mode == 0 {
+		wal.Walk(val, state.Vis)
 	} else {
-		self.Map[ref] = val
+		state.walk(wal, val)
 	}
-	return val
 }
 
 /**
@@ -94,9 +311,75 @@ func (self fieldRef) StructField() (_ r.StructField) {
 	return self.Parent.Field(self.Index)
 }
 
+/**
+Walk modes affect the generated walkers, and are part of walker cache keys.
+Walkers generated for the default zero mode are used with the user's visitor.
+Walkers generated for any other mode must be used with `*walkState`, which
+wraps the user's visitor and holds per-walk state.
+*/
+type walkMode byte
+
+const (
+	// Walk each pointer target at most once. Used by `rf.WalkOnce`.
+	walkModeOnce walkMode = 1 << iota
+
+	// Track the path from the root. Used by `rf.WalkPath`.
+	walkModePath
+
+	// Allow the visitor to skip descendants or stop. Used by `rf.WalkErr`.
+	walkModeCtl
+
+	// Call `LeaveVisitor.Leave` after visiting each node. Used by `rf.Walk`.
+	walkModeLeave
+
+	// Walk list elements concurrently. Used by `rf.WalkParallel`.
+	walkModePar
+
+	// Treat value-embedded structs as parts of the enclosing struct, using
+	// fields from `rf.TypeDeepFields`. Used by `rf.WalkFlat`.
+	walkModeFlat
+
+	// Walk settable copies of the dynamic values of interfaces and of map
+	// values, storing the copies back after walking them. Map keys are not
+	// walked. Used by `rf.Redact`.
+	walkModeWrite
+)
+
+func (self walkMode) once() bool  { return (self & walkModeOnce) != 0 }
+func (self walkMode) path() bool  { return (self & walkModePath) != 0 }
+func (self walkMode) ctl() bool   { return (self & walkModeCtl) != 0 }
+func (self walkMode) leave() bool { return (self & walkModeLeave) != 0 }
+func (self walkMode) par() bool   { return (self & walkModePar) != 0 }
+func (self walkMode) flat() bool  { return (self & walkModeFlat) != 0 }
+func (self walkMode) write() bool { return (self & walkModeWrite) != 0 }
+
+/**
+True if walkers with multiple children (structs, lists, maps) must use variants
+which maintain per-walk state between children.
+*/
+func (self walkMode) stateful() bool { return self.path() || self.ctl() }
+
+/**
+`.Depth` is the depth of the node for filters which implement `rf.DepthFilter`
+(see `usesDepth`), and zero for other filters. This allows walkers generated
+for other filters to ignore depth, reusing walkers for cyclic types.
+*/
 type filterRef struct {
 	fieldRef
 	Filter Filter
+	Mode   walkMode
+	Depth  int
+}
+
+/**
+Like `fieldRef.StructField`, but in `walkModeFlat`, `.Index` refers to the field
+in `rf.TypeDeepFields` for `.Parent`, rather than the direct field.
+*/
+func (self filterRef) StructField() r.StructField {
+	if self.Mode.flat() && self.Parent != nil {
+		return TypeDeepFields(self.Parent)[self.Index]
+	}
+	return self.fieldRef.StructField()
 }
 
 func (self filterRef) walkRef(typ r.Type) (out walkRef) {
@@ -119,17 +402,200 @@ func (self walkRef) validate() { validateFilter(self.Filter) }
 
 func (self walkRef) vis() (_ vis) {
 	if self.Type != nil && self.Filter != nil {
-		return vis(self.Filter.Visit(self.Type, self.StructField()))
+		return visitNode(self.Filter, self.Type, self.StructField(), self.Depth)
 	}
 	return
 }
 
-func (self walkRef) makeWalker() Walker {
+func (self walkRef) makeWalker(cache *WalkerCache) Walker {
 	var bui walkBui
 	bui.walkRef = self
+	bui.cache = cache
+	bui.build = new(walkBuild)
+	bui.build.analyze(self)
 	return bui.makeWalker()
 }
 
+/**
+Children of the node which would have walkers, in the same order and with the
+same derivation as in `walkBui`. Nodes whose descendants are not walked have no
+children.
+*/
+func (self walkRef) children(fun func(walkRef)) {
+	if self.Type == nil || self.Filter == nil {
+		return
+	}
+
+	vis := self.vis()
+	if !vis.desc() {
+		return
+	}
+
+	switch self.Type.Kind() {
+	case r.Ptr:
+		fun(self.elem())
+
+	case r.Array, r.Slice:
+		fun(self.elemDeep())
+
+	case r.Struct:
+		if self.Mode.flat() {
+			for ind, field := range TypeDeepFields(self.Type) {
+				if isFieldPathPublic(self.Type, field.Index) || visitPrivate(self.Filter, field.Type, field) {
+					fun(self.field(ind))
+				}
+			}
+			return
+		}
+
+		for ind := range Iter(self.Type.NumField()) {
+			field := self.Type.Field(ind)
+			if IsFieldPublic(field) || visitPrivate(self.Filter, field.Type, field) {
+				fun(self.field(ind))
+			}
+		}
+
+	case r.Map:
+		if vis.key() && !self.Mode.write() {
+			fun(self.key())
+		}
+		if vis.val() {
+			fun(self.elemDeep())
+		}
+	}
+}
+
+/**
+True if the node has a walker regardless of its children: it's visited, or
+it's an interface whose dynamic values are walked.
+*/
+func (self walkRef) live() bool {
+	vis := self.vis()
+	return vis.self() || (vis.desc() && self.Type.Kind() == r.Interface)
+}
+
+// Pointer target, which has the same depth as the pointer.
+func (self walkRef) elem() walkRef {
+	self.Type = self.Type.Elem()
+	return self
+}
+
+// List element or map value, one level deeper than its parent.
+func (self walkRef) elemDeep() walkRef {
+	out := self.elem()
+	out.Depth = self.depth()
+	return out
+}
+
+func (self walkRef) key() walkRef {
+	out := self
+	out.Type = self.Type.Key()
+	out.Depth = self.depth()
+	return out
+}
+
+// In `walkModeFlat`, the index is for `rf.TypeDeepFields`. See `filterRef`.
+func (self walkRef) field(index int) walkRef {
+	out := self
+	out.Parent = self.Type
+	out.Index = index
+	out.Type = out.StructField().Type
+	out.Depth = self.depth()
+	return out
+}
+
+/**
+Depth of the children of lists, maps and structs, but not pointers, whose
+targets have the same depth. See `rf.DepthFilter` and `filterRef`.
+*/
+func (self walkRef) depth() int {
+	if usesDepth(self.Filter) {
+		if self.Depth >= walkDepthLimit {
+			panic(errWalkDepthLimit(self))
+		}
+		return self.Depth + 1
+	}
+	return 0
+}
+
+/**
+Limit on the depth of nodes for filters which implement `rf.DepthFilter`.
+Because such filters may return different results at different depths, each
+level of a cyclic type gets its own walker, and generating walkers terminates
+only when the filter stops descending. Exceeding this limit indicates a filter
+which keeps descending into a cyclic type.
+*/
+const walkDepthLimit = 1024
+
+func errWalkDepthLimit(ref walkRef) Err {
+	return Err{
+		`generating walker`,
+		fmt.Errorf(
+			`depth-aware filter %#v exceeded the depth limit %v at type %v; depth-aware filters must stop descending into cyclic types, for example via rf.MaxDepth`,
+			ref.Filter, walkDepthLimit, ref.Type,
+		),
+	}
+}
+
+/**
+State shared by all builders during one build. Building walkers happens in two
+phases, each linear in the count of distinct `walkRef` reachable from the root:
+
+	* `.analyze` finds which nodes have non-nil walkers, as a fixpoint over
+	  the graph of nodes, which may be cyclic.
+
+	* `walkBui` builds the walkers for those nodes, building each at most once
+	  and memoizing the result.
+
+Knowing in advance which nodes have walkers allows to build cyclic types in a
+single pass: an inner occurrence of a pending node can immediately reference
+the walker of the outer occurrence.
+*/
+type walkBuild struct {
+	live map[walkRef]bool
+	memo map[walkRef]Walker
+}
+
+func (self *walkBuild) analyze(root walkRef) {
+	self.live = map[walkRef]bool{}
+	self.memo = map[walkRef]Walker{}
+
+	parents := map[walkRef][]walkRef{}
+	seen := map[walkRef]struct{}{root: {}}
+	queue := []walkRef{root}
+	var live []walkRef
+
+	for len(queue) > 0 {
+		ref := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+
+		if ref.live() {
+			self.live[ref] = true
+			live = append(live, ref)
+		}
+
+		ref.children(func(sub walkRef) {
+			parents[sub] = append(parents[sub], ref)
+			if _, ok := seen[sub]; !ok {
+				seen[sub] = struct{}{}
+				queue = append(queue, sub)
+			}
+		})
+	}
+
+	for len(live) > 0 {
+		ref := live[len(live)-1]
+		live = live[:len(live)-1]
+
+		for _, par := range parents[ref] {
+			if !self.live[par] {
+				self.live[par] = true
+				live = append(live, par)
+			}
+		}
+	}
+}
+
 /**
 The term "bui" is short for "builder". This wrapper allows us to detect cyclic
 types and avoid infinite recursion / stack overflow when attempting to build a
@@ -137,49 +603,76 @@ walker for inner occurrences of the same outer type. The parent pointer refers
 to an earlier stack frame, using the language's own stack for book keeping, and
 avoiding the need for another data structure.
 
-Currently, we simply skip inner occurrences of an outer type, generating a nil
-walker. The outermost occurrence of any given type is walked as usual, but its
-inner occurrences are not walked. This limitation is due to technical
-difficulties, and we would like to lift it in the future.
+When an inner node has the same `walkRef` as one of its pending ancestors, it
+reuses the ancestor's walker via a lazily bound `refWalker`, which allows to
+fully walk cyclic types. Whether the ancestor's walker is nil is known in
+advance, see `walkBuild`.
 */
 type walkBui struct {
 	walkRef
 	parent *walkBui
+	cache  *WalkerCache
+	build  *walkBuild
+	ref    *refWalker
 }
 
-func (self *walkBui) isCyclic() bool {
-	return self.parent != nil &&
-		self.Type != nil &&
-		self.parent.isTypePending(self.Type)
+func (self *walkBui) pending(ref walkRef) *walkBui {
+	for bui := self; bui != nil; bui = bui.parent {
+		if bui.walkRef == ref {
+			return bui
+		}
+	}
+	return nil
 }
 
-func (self *walkBui) isTypePending(typ r.Type) bool {
-	return typ == self.Type ||
-		(self.parent != nil && self.parent.isTypePending(typ))
+// Called for inner occurrences of a pending node, whose walker is non-nil.
+func (self *walkBui) refer() Walker {
+	if self.ref == nil {
+		self.ref = new(refWalker)
+	}
+	return self.ref
 }
 
-func (self *walkBui) elem() (out walkBui) {
-	out.walkRef = self.walkRef
-	out.Type = self.Type.Elem()
+func (self *walkBui) sub(ref walkRef) (out walkBui) {
+	out.walkRef = ref
 	out.parent = self
+	out.cache = self.cache
+	out.build = self.build
 	return
 }
 
-func (self *walkBui) field(index int) (out walkBui) {
-	field := self.Type.Field(index)
-	out.Type = field.Type
-	out.Parent = self.Type
-	out.Index = index
-	out.Filter = self.Filter
-	out.parent = self
-	return
+func (self *walkBui) elem() walkBui     { return self.sub(self.walkRef.elem()) }
+func (self *walkBui) elemDeep() walkBui { return self.sub(self.walkRef.elemDeep()) }
+func (self *walkBui) key() walkBui      { return self.sub(self.walkRef.key()) }
+
+func (self *walkBui) field(index int) walkBui {
+	return self.sub(self.walkRef.field(index))
 }
 
 func (self *walkBui) makeWalker() Walker {
-	if self.Type == nil || self.Filter == nil || self.isCyclic() {
+	if self.Type == nil || self.Filter == nil || !self.build.live[self.walkRef] {
 		return nil
 	}
 
+	out, ok := self.build.memo[self.walkRef]
+	if ok {
+		return out
+	}
+
+	anc := self.parent.pending(self.walkRef)
+	if anc != nil {
+		return anc.refer()
+	}
+
+	out = self.makeKindWalker()
+	if self.ref != nil {
+		self.ref[0] = out
+	}
+	self.build.memo[self.walkRef] = out
+	return out
+}
+
+func (self *walkBui) makeKindWalker() Walker {
 	switch self.Type.Kind() {
 	case r.Ptr:
 		return self.makePtrWalker()
@@ -187,6 +680,8 @@ func (self *walkBui) makeWalker() Walker {
 		return self.makeListWalker()
 	case r.Struct:
 		return self.makeStructWalker()
+	case r.Map:
+		return self.makeMapWalker()
 	case r.Interface:
 		return self.makeIfaceWalker()
 	default:
@@ -200,6 +695,9 @@ func (self *walkBui) makePtrWalker() Walker {
 		inner := sub.makeWalker()
 
 		if inner != nil {
+			if self.Mode.once() {
+				return self.makeNodeWalker(ptrOnceWalker{inner})
+			}
 			return self.makeNodeWalker(ptrWalker{inner})
 		}
 	}
@@ -209,10 +707,16 @@ func (self *walkBui) makePtrWalker() Walker {
 
 func (self *walkBui) makeListWalker() Walker {
 	if self.vis().desc() {
-		sub := self.elem()
+		sub := self.elemDeep()
 		inner := sub.makeWalker()
 
 		if inner != nil {
+			if self.Mode.stateful() {
+				return self.makeNodeWalker(listModeWalker{inner, self.Mode})
+			}
+			if self.Mode.par() {
+				return self.makeNodeWalker(listParWalker{inner})
+			}
 			return self.makeNodeWalker(listWalker{inner})
 		}
 	}
@@ -221,6 +725,10 @@ func (self *walkBui) makeListWalker() Walker {
 }
 
 func (self *walkBui) makeStructWalker() Walker {
+	if self.Mode.flat() {
+		return self.makeStructFlatWalker()
+	}
+
 	if self.vis().desc() {
 		var tar structWalker
 		for ind := range Iter(self.Type.NumField()) {
@@ -228,6 +736,59 @@ func (self *walkBui) makeStructWalker() Walker {
 		}
 
 		if len(tar) > 0 {
+			if self.Mode.stateful() {
+				return self.makeNodeWalker(structModeWalker{tar, self.Mode})
+			}
+			return self.makeNodeWalker(tar)
+		}
+	}
+
+	return self.makeLeafWalker()
+}
+
+/**
+Variant of `makeStructWalker` for `walkModeFlat`. Walks deep fields instead of
+direct fields. Value-embedded structs are not nodes in their own right, and
+the filter is not consulted about them.
+*/
+func (self *walkBui) makeStructFlatWalker() Walker {
+	if self.vis().desc() {
+		var tar structFlatWalker
+		for ind := range TypeDeepFields(self.Type) {
+			tar.maybeAppend(self.makeFieldPathWalker(ind))
+		}
+
+		if len(tar) > 0 {
+			return self.makeNodeWalker(tar)
+		}
+	}
+
+	return self.makeLeafWalker()
+}
+
+func (self *walkBui) makeMapWalker() Walker {
+	vis := self.vis()
+
+	if vis.desc() {
+		var tar mapWalker
+
+		if vis.key() && !self.Mode.write() {
+			sub := self.key()
+			tar.Key = sub.makeWalker()
+		}
+
+		if vis.val() {
+			sub := self.elemDeep()
+			tar.Val = sub.makeWalker()
+		}
+
+		if tar.isValid() {
+			if self.Mode.write() {
+				return self.makeNodeWalker(mapWriteWalker(tar))
+			}
+			if self.Mode.stateful() {
+				return self.makeNodeWalker(mapModeWalker(tar))
+			}
 			return self.makeNodeWalker(tar)
 		}
 	}
@@ -239,7 +800,10 @@ func (self *walkBui) makeStructWalker() Walker {
 func (self *walkBui) makeFieldIndexWalker(index int) (out fieldIndexWalker) {
 	field := self.Type.Field(index)
 	if !IsFieldPublic(field) {
-		return
+		if !visitPrivate(self.Filter, field.Type, field) {
+			return
+		}
+		out.Private = true
 	}
 
 	sub := self.field(index)
@@ -248,9 +812,44 @@ func (self *walkBui) makeFieldIndexWalker(index int) (out fieldIndexWalker) {
 	return
 }
 
+/**
+Variant of `makeFieldIndexWalker` for `walkModeFlat`. A deep field is private
+when it's private itself, or when any of the embedded structs containing it is
+private. Note: returned walker may be invalid.
+*/
+func (self *walkBui) makeFieldPathWalker(index int) (out fieldPathWalker) {
+	field := TypeDeepFields(self.Type)[index]
+	if !isFieldPathPublic(self.Type, field.Index) {
+		if !visitPrivate(self.Filter, field.Type, field) {
+			return
+		}
+		out.Private = true
+	}
+
+	sub := self.field(index)
+	out.Path = field.Index
+	out.Inner = sub.makeWalker()
+	return
+}
+
+func isFieldPathPublic(typ r.Type, path []int) bool {
+	for _, ind := range path {
+		field := typ.Field(ind)
+		if !IsFieldPublic(field) {
+			return false
+		}
+		typ = field.Type
+	}
+	return true
+}
+
 func (self *walkBui) makeIfaceWalker() Walker {
 	if self.vis().desc() {
-		return self.makeNodeWalker(ifaceWalker(self.filterRef))
+		tar := ifaceWalker{self.filterRef, self.cache}
+		if self.Mode.write() {
+			return self.makeNodeWalker(ifaceWriteWalker(tar))
+		}
+		return self.makeNodeWalker(tar)
 	}
 	return self.makeLeafWalker()
 }
@@ -260,8 +859,22 @@ func (self *walkBui) makeNodeWalker(inner Walker) Walker {
 		panic(errUselessNodeWalker)
 	}
 
-	if self.vis().self() {
-		field := self.StructField()
+	out := self.makeSelfWalker(inner)
+	if usesValue(self.Filter) {
+		return self.makeValueWalker(out, self.makeSelfLeafWalker(), inner)
+	}
+	return out
+}
+
+func (self *walkBui) makeSelfWalker(inner Walker) Walker {
+	if self.vis().self() {
+		field := self.StructField()
+		if self.Mode.ctl() {
+			return selfCtlWalker{field, inner}
+		}
+		if self.Mode.leave() {
+			return selfLeaveWalker{field, inner}
+		}
 		if isFieldValid(field) {
 			return selfFieldWalker{field, inner}
 		}
@@ -276,8 +889,34 @@ var errUselessNodeWalker = Err{
 }
 
 func (self *walkBui) makeLeafWalker() Walker {
+	out := self.makeSelfLeafWalker()
+	if out != nil && usesValue(self.Filter) {
+		return self.makeValueWalker(out, out, nil)
+	}
+	return out
+}
+
+func (self *walkBui) makeValueWalker(both, slf, desc Walker) Walker {
+	depth := -1
+	if usesDepth(self.Filter) {
+		depth = self.Depth
+	}
+	return valueWalker{
+		Filter: self.Filter.(ValueFilter),
+		Field:  self.StructField(),
+		Depth:  depth,
+		Both:   both,
+		Self:   slf,
+		Desc:   desc,
+	}
+}
+
+func (self *walkBui) makeSelfLeafWalker() Walker {
 	if self.vis().self() {
 		field := self.StructField()
+		if self.Mode.leave() {
+			return leafLeaveWalker(field)
+		}
 		if isFieldValid(field) {
 			return leafFieldWalker(field)
 		}
@@ -286,6 +925,45 @@ func (self *walkBui) makeLeafWalker() Walker {
 	return nil
 }
 
+/**
+Used only for filters implementing `rf.ValueFilter` (see `usesValue`). Consults
+the filter for each walked value, choosing between walkers prepared in advance
+for each combination of flags allowed by the static filter. Any of them may be
+nil when the static filter doesn't allow the given combination. `.Depth` is
+negative when the filter doesn't use depth.
+*/
+type valueWalker struct {
+	Filter ValueFilter
+	Field  r.StructField
+	Depth  int
+	Both   Walker
+	Self   Walker
+	Desc   Walker
+}
+
+func (self valueWalker) Walk(val r.Value, vis Visitor) {
+	var wal Walker
+	switch self.visit(val) & VisBoth {
+	case VisBoth:
+		wal = self.Both
+	case VisSelf:
+		wal = self.Self
+	case VisDesc:
+		wal = self.Desc
+	}
+	if wal != nil {
+		wal.Walk(val, vis)
+	}
+}
+
+func (self valueWalker) visit(val r.Value) byte {
+	impl, _ := self.Filter.(valueUser)
+	if impl != nil {
+		return impl.visitValue(val, self.Field, self.Depth)
+	}
+	return self.Filter.VisitValue(val, self.Field)
+}
+
 type selfWalker [1]Walker
 
 func (self selfWalker) Walk(val r.Value, vis Visitor) {
@@ -303,6 +981,43 @@ func (self selfFieldWalker) Walk(val r.Value, vis Visitor) {
 	self.Inner.Walk(val, vis)
 }
 
+/**
+Variant of `selfWalker` and `selfFieldWalker` for `walkModeCtl`. Walks the
+descendants only if the visitor didn't request to skip them or to stop.
+*/
+type selfCtlWalker struct {
+	Field r.StructField
+	Inner Walker
+}
+
+func (self selfCtlWalker) Walk(val r.Value, vis Visitor) {
+	vis.Visit(val, self.Field)
+
+	state := vis.(*walkState)
+	if state.Skip {
+		state.Skip = false
+		return
+	}
+	if !state.Done {
+		self.Inner.Walk(val, vis)
+	}
+}
+
+/**
+Variant of `selfWalker` and `selfFieldWalker` for `walkModeLeave`. Calls
+`LeaveVisitor.Leave` after walking the descendants.
+*/
+type selfLeaveWalker struct {
+	Field r.StructField
+	Inner Walker
+}
+
+func (self selfLeaveWalker) Walk(val r.Value, vis Visitor) {
+	vis.Visit(val, self.Field)
+	self.Inner.Walk(val, vis)
+	vis.(*walkState).Leave(val, self.Field)
+}
+
 type ptrWalker [1]Walker
 
 func (self ptrWalker) Walk(val r.Value, vis Visitor) {
@@ -311,6 +1026,19 @@ func (self ptrWalker) Walk(val r.Value, vis Visitor) {
 	}
 }
 
+/**
+Variant of `ptrWalker` for `walkModeOnce`. Walks the target of any given pointer
+at most once per walk, which prevents infinite recursion on pointer cycles and
+avoids walking shared targets repeatedly.
+*/
+type ptrOnceWalker [1]Walker
+
+func (self ptrOnceWalker) Walk(val r.Value, vis Visitor) {
+	if !val.IsNil() && vis.(*walkState).addPtr(val) {
+		self[0].Walk(val.Elem(), vis)
+	}
+}
+
 type listWalker [1]Walker
 
 func (self listWalker) Walk(val r.Value, vis Visitor) {
@@ -319,6 +1047,129 @@ func (self listWalker) Walk(val r.Value, vis Visitor) {
 	}
 }
 
+/**
+Variant of `listWalker` for stateful modes (see `walkMode.stateful`). Appends
+the index of each element to the path when tracking the path, and stops when
+the walk is done.
+*/
+type listModeWalker struct {
+	Inner Walker
+	Mode  walkMode
+}
+
+func (self listModeWalker) Walk(val r.Value, vis Visitor) {
+	state := vis.(*walkState)
+
+	for ind := range Iter(val.Len()) {
+		if state.Done {
+			return
+		}
+		if self.Mode.path() {
+			self.walkPath(val.Index(ind), state, ind)
+		} else {
+			self.Inner.Walk(val.Index(ind), state)
+		}
+	}
+}
+
+func (self listModeWalker) walkPath(val r.Value, state *walkState, ind int) {
+	defer state.Path.Add([]int{ind}).Reset()
+	self.Inner.Walk(val, state)
+}
+
+/**
+Variant of `listWalker` for `walkModePar`. Splits the elements into chunks of at
+least `parChunkMin`, walking them in separate goroutines as long as the
+per-walk worker limit allows it, and walking the rest in the current
+goroutine. Nested lists are split in the same way, sharing the same limit.
+Panics in other goroutines are propagated to the current goroutine.
+*/
+type listParWalker [1]Walker
+
+const parChunkMin = 256
+
+func (self listParWalker) Walk(val r.Value, vis Visitor) {
+	state := vis.(*walkState)
+	count := val.Len()
+	size := divCeil(count, cap(state.Sem)+1)
+	if size < parChunkMin {
+		size = parChunkMin
+	}
+
+	var group sync.WaitGroup
+	var fail parPanic
+
+	for start := 0; start < count; start += size {
+		end := start + size
+		if end > count {
+			end = count
+		}
+
+		if end < count && state.acquire() {
+			group.Add(1)
+			go self.walkAsync(val, state, start, end, &group, &fail)
+		} else if !self.walkSync(val, state, start, end, &fail) {
+			break
+		}
+	}
+
+	group.Wait()
+	fail.rethrow()
+}
+
+/**
+Catches panics in the calling goroutine, like `walkAsync` in other goroutines,
+making sure that we wait for other goroutines before rethrowing. Returns false
+on panic.
+*/
+func (self listParWalker) walkSync(
+	val r.Value, state *walkState, start, end int, fail *parPanic,
+) (ok bool) {
+	defer fail.catch()
+	self.walk(val, state, start, end)
+	return true
+}
+
+func (self listParWalker) walkAsync(
+	val r.Value, state *walkState, start, end int, group *sync.WaitGroup, fail *parPanic,
+) {
+	defer group.Done()
+	defer state.release()
+	defer fail.catch()
+	self.walk(val, state, start, end)
+}
+
+func (self listParWalker) walk(val r.Value, state *walkState, start, end int) {
+	for ind := start; ind < end; ind++ {
+		self[0].Walk(val.Index(ind), state)
+	}
+}
+
+/**
+Stores the first panic from any of the goroutines involved in a parallel walk,
+for rethrowing. Also used by `refBuild`.
+*/
+type parPanic struct {
+	sync.Once
+	Val any
+	Ok  bool
+}
+
+func (self *parPanic) catch() {
+	val := recover()
+	if val != nil {
+		self.Do(func() { self.Val, self.Ok = val, true })
+	}
+}
+
+func (self *parPanic) rethrow() {
+	if self.Ok {
+		panic(self.Val)
+	}
+}
+
+func divCeil(val, div int) int { return (val + div - 1) / div }
+
 type structWalker []fieldIndexWalker
 
 func (self structWalker) Walk(val r.Value, vis Visitor) {
@@ -333,6 +1184,119 @@ func (self *structWalker) maybeAppend(val fieldIndexWalker) {
 	}
 }
 
+/**
+Either of the inner walkers may be nil, but not both.
+
+Keys and values are obtained via `reflect.MapIter.Key` and
+`reflect.MapIter.Value`, which return non-addressable copies. Copying them into
+reusable temporary variables would avoid allocations, but would make them
+addressable, allowing visitors such as `rf.PtrAppender` to retain pointers to
+the temporaries. `mapWriteWalker` does use temporary variables, because it needs
+settable values.
+*/
+type mapWalker struct {
+	Key Walker
+	Val Walker
+}
+
+func (self mapWalker) Walk(val r.Value, vis Visitor) {
+	if val.Len() == 0 {
+		return
+	}
+
+	iter := getMapIter(val)
+	defer putMapIter(iter)
+
+	for iter.Next() {
+		if self.Key != nil {
+			self.Key.Walk(iter.Key(), vis)
+		}
+		if self.Val != nil {
+			self.Val.Walk(iter.Value(), vis)
+		}
+	}
+}
+
+/**
+Variant of `mapWalker` for stateful modes. Map entries don't contribute to the
+path (see `rf.PathVisitor`), so this only needs to stop when the walk is done.
+*/
+type mapModeWalker mapWalker
+
+func (self mapModeWalker) Walk(val r.Value, vis Visitor) {
+	if val.Len() == 0 {
+		return
+	}
+
+	state := vis.(*walkState)
+	iter := getMapIter(val)
+	defer putMapIter(iter)
+
+	for !state.Done && iter.Next() {
+		if self.Key != nil {
+			self.Key.Walk(iter.Key(), vis)
+		}
+		if self.Val != nil && !state.Done {
+			self.Val.Walk(iter.Value(), vis)
+		}
+	}
+}
+
+/**
+Variant of `mapWalker` for `walkModeWrite`, which stores each walked value back
+into the map. Unlike `mapModeWalker`, this is not stateful, because the only
+user of this mode, `rf.Redact`, doesn't need the state.
+*/
+type mapWriteWalker mapWalker
+
+func (self mapWriteWalker) Walk(val r.Value, vis Visitor) {
+	if val.Len() == 0 {
+		return
+	}
+
+	iter := getMapIter(val)
+	defer putMapIter(iter)
+
+	typ := val.Type()
+	key := r.New(typ.Key()).Elem()
+	elem := r.New(typ.Elem()).Elem()
+
+	for iter.Next() {
+		key.SetIterKey(iter)
+		elem.SetIterValue(iter)
+		self.Val.Walk(elem, vis)
+		val.SetMapIndex(key, elem)
+	}
+}
+
+func (self mapWalker) isValid() bool { return self.Key != nil || self.Val != nil }
+
+// Variant of `structWalker` for stateful modes. See `listModeWalker`.
+type structModeWalker struct {
+	Fields structWalker
+	Mode   walkMode
+}
+
+func (self structModeWalker) Walk(val r.Value, vis Visitor) {
+	state := vis.(*walkState)
+
+	for _, walker := range self.Fields {
+		if state.Done {
+			return
+		}
+		if self.Mode.path() {
+			self.walkPath(val, state, walker)
+		} else {
+			walker.Walk(val, state)
+		}
+	}
+}
+
+func (structModeWalker) walkPath(val r.Value, state *walkState, walker fieldIndexWalker) {
+	defer state.Path.Add([]int{walker.Index}).Reset()
+	walker.Walk(val, state)
+}
+
 /**
 Implementation note: it may seem unintuitive that this walker does not store
 `reflect.StructField`, while some other walkers do store it. That's due to the
@@ -341,8 +1305,9 @@ the inner walker without providing the field (see the `Walker` interface). The
 innermost walker stores the field and provides it to the visitor.
 */
 type fieldIndexWalker struct {
-	Index int
-	Inner Walker
+	Index   int
+	Inner   Walker
+	Private bool
 }
 
 /**
@@ -350,12 +1315,73 @@ Does not nil-check `.Inner` because when `.Inner` is nil, this should be
 excluded from `structWalker`. Having a nil inner walker would be a bug.
 */
 func (self fieldIndexWalker) Walk(val r.Value, vis Visitor) {
-	self.Inner.Walk(val.Field(self.Index), vis)
+	if self.Private {
+		self.Inner.Walk(privateField(val, self.Index), vis)
+	} else {
+		self.Inner.Walk(val.Field(self.Index), vis)
+	}
 }
 
 func (self fieldIndexWalker) isValid() bool { return self.Inner != nil }
 
-type ifaceWalker filterRef
+// Variant of `structWalker` for `walkModeFlat`.
+type structFlatWalker []fieldPathWalker
+
+func (self structFlatWalker) Walk(val r.Value, vis Visitor) {
+	for _, walker := range self {
+		walker.Walk(val, vis)
+	}
+}
+
+func (self *structFlatWalker) maybeAppend(val fieldPathWalker) {
+	if val.isValid() {
+		*self = append(*self, val)
+	}
+}
+
+/**
+Variant of `fieldIndexWalker` for `walkModeFlat`. The path is the index of a
+field from `rf.TypeDeepFields`, which may go through value-embedded structs,
+but never through pointers, so it can't encounter nil.
+*/
+type fieldPathWalker struct {
+	Path    []int
+	Inner   Walker
+	Private bool
+}
+
+func (self fieldPathWalker) Walk(val r.Value, vis Visitor) {
+	if self.Private {
+		for _, ind := range self.Path {
+			val = privateField(val, ind)
+		}
+	} else {
+		val = val.FieldByIndex(self.Path)
+	}
+	self.Inner.Walk(val, vis)
+}
+
+func (self fieldPathWalker) isValid() bool { return self.Inner != nil }
+
+/**
+Lazily bound reference to the walker of an outer occurrence of a cyclic type.
+Created and bound by `walkBui`, see the comment there. Must not be walked
+before binding, which is guaranteed by the builder: the reference becomes
+reachable from the outside only after the build is complete.
+*/
+type refWalker [1]Walker
+
+func (self *refWalker) Walk(val r.Value, vis Visitor) { self[0].Walk(val, vis) }
+
+/**
+Gets or makes walkers for dynamic values via the cache which made this walker.
+Walkers keep working after being evicted from their cache, or after the cache
+is cleared, re-populating it as needed.
+*/
+type ifaceWalker struct {
+	Ref   filterRef
+	Cache *WalkerCache
+}
 
 func (self ifaceWalker) Walk(val r.Value, vis Visitor) {
 	if val.IsNil() {
@@ -363,91 +1389,1358 @@ func (self ifaceWalker) Walk(val r.Value, vis Visitor) {
 	}
 
 	val = val.Elem()
-	walker := walkerCacheStatic.getOrMakeFor(filterRef(self).walkRef(val.Type()))
+	walker := self.Cache.getOrMakeFor(self.Ref.walkRef(val.Type()))
 	if walker != nil {
 		walker.Walk(val, vis)
 	}
 }
 
+/**
+Variant of `ifaceWalker` for `walkModeWrite`. Dynamic values other than pointers
+are not addressable, so this walks a copy and stores it back.
+*/
+type ifaceWriteWalker ifaceWalker
+
+func (self ifaceWriteWalker) Walk(val r.Value, vis Visitor) {
+	if val.IsNil() {
+		return
+	}
+
+	elem := val.Elem()
+	walker := self.Cache.getOrMakeFor(self.Ref.walkRef(elem.Type()))
+	if walker == nil {
+		return
+	}
+
+	if elem.Kind() == r.Ptr || !val.CanSet() {
+		walker.Walk(elem, vis)
+		return
+	}
+
+	tar := r.New(elem.Type()).Elem()
+	tar.Set(elem)
+	walker.Walk(tar, vis)
+	val.Set(tar)
+}
+
 type leafFieldWalker r.StructField
 
 func (self leafFieldWalker) Walk(val r.Value, vis Visitor) {
 	vis.Visit(val, r.StructField(self))
 }
 
-type leafWalker struct{}
+// Variant of `leafFieldWalker` and `leafWalker` for `walkModeLeave`.
+type leafLeaveWalker r.StructField
 
-func (leafWalker) Walk(val r.Value, vis Visitor) {
-	vis.Visit(val, r.StructField{})
+func (self leafLeaveWalker) Walk(val r.Value, vis Visitor) {
+	vis.Visit(val, r.StructField(self))
+	vis.(*walkState).Leave(val, r.StructField(self))
 }
 
-func validateFilter(src Filter) {
-	validateFilterValue(src, r.ValueOf(src))
+/**
+Used by appenders and other visitors collecting values of a known type.
+Avoids copying values into interfaces when possible.
+*/
+func valueOf[A any](val r.Value) A {
+	if val.CanAddr() {
+		return *val.Addr().Interface().(*A)
+	}
+	return val.Interface().(A)
 }
 
-func validateFilterValue(src Filter, val r.Value) {
-	switch val.Kind() {
-	case r.Chan, r.Func, r.Map, r.Ptr, r.Slice, r.UnsafePointer:
-		panic(errInvalidFilter(src, val))
-	case r.Array:
-		validateFilterArray(src, val)
-	case r.Struct:
-		validateFilterStruct(src, val)
-	case r.Interface:
-		switch val.Type() {
-		case typeType:
-		case typeFilter:
-			if !val.IsNil() {
-				validateFilterValue(src, val.Elem())
-			}
-		default:
-			panic(errInvalidFilter(src, val))
-		}
+// Visitor used by `rf.TrawlFirst`.
+type firstVisitor[A any] struct {
+	Val A
+	Ok  bool
+}
+
+func (self *firstVisitor[A]) VisitErr(val r.Value, _ r.StructField) error {
+	if val.IsZero() {
+		return nil
 	}
+	self.Val = valueOf[A](val)
+	self.Ok = true
+	return SkipAll
 }
 
-func validateFilterArray(src Filter, val r.Value) {
-	for ind := range Iter(val.Len()) {
-		validateFilterValue(src, val.Index(ind))
+// Visitor used by `rf.Count`.
+type countVisitor int
+
+func (self *countVisitor) Visit(val r.Value, _ r.StructField) {
+	if !val.IsZero() {
+		*self++
 	}
 }
 
-func validateFilterStruct(src Filter, val r.Value) {
-	for ind := range Iter(val.NumField()) {
-		validateFilterValue(src, val.Field(ind))
+// Visitor used by `rf.Values`.
+type valueYielder func(r.Value, r.StructField) bool
+
+func (self valueYielder) VisitErr(val r.Value, field r.StructField) error {
+	if self(val, field) {
+		return nil
 	}
+	return SkipAll
 }
 
-func errInvalidFilter(src Filter, val r.Value) Err {
-	return Err{
-		`validating walk filter`,
-		fmt.Errorf(`invalid filter %#v: contains %v of kind %v`, src, val, val.Kind()),
+// Visitor used by `rf.Each`.
+type ptrYielder[A any] func(*A) bool
+
+func (self ptrYielder[A]) VisitErr(val r.Value, _ r.StructField) error {
+	if !val.CanAddr() || self(val.Addr().Interface().(*A)) {
+		return nil
 	}
+	return SkipAll
 }
 
-func maybeCombineFilters(src, out []Filter) []Filter {
-	for _, val := range src {
-		if val == nil {
-			continue
-		}
+// Visitor used by `rf.Rewrite`.
+type rewriter[A any] func(A) A
 
-		if len(out) >= cap(out) {
-			panic(Err{
-				`building a combined filter`,
-				fmt.Errorf(`exceeding filter capacity %v`, cap(out)),
-			})
-		}
-		out = append(out, val)
+func (self rewriter[A]) Visit(val r.Value, _ r.StructField) {
+	if val.CanAddr() {
+		ptr := val.Addr().Interface().(*A)
+		*ptr = self(*ptr)
 	}
-	return out
 }
 
-func ifaceVisit(visTyp, ifaceTyp r.Type, hit byte) byte {
-	if visTyp == nil || ifaceTyp == nil {
-		return VisNone
+// Visitor used by `rf.RewritePtr`.
+type ptrRewriter[A any] func(*A)
+
+func (self ptrRewriter[A]) Visit(val r.Value, _ r.StructField) {
+	if val.CanAddr() {
+		self(val.Addr().Interface().(*A))
+	}
+}
+
+type leafWalker struct{}
+
+func (leafWalker) Walk(val r.Value, vis Visitor) {
+	vis.Visit(val, r.StructField{})
+}
+
+/**
+Renders a walker tree for `rf.Explain`. Walkers don't store their types and
+fields, so we derive them alongside, the same way `walkBui` does when building
+walkers. The filter is needed only to display the flags of each node.
+*/
+type walkExplain struct {
+	strings.Builder
+	Filter Filter
+}
+
+/**
+The indent is the nesting level of the rendered tree, while the depth is the
+depth of the node for `rf.DepthFilter`, which doesn't count pointers.
+*/
+func (self *walkExplain) node(
+	indent, depth int, label string, typ r.Type, field r.StructField, wal Walker, private bool,
+) {
+	_, value := wal.(valueWalker)
+	wal = explainInner(wal)
+
+	if self.Len() > 0 {
+		self.WriteByte('\n')
+	}
+	for range Iter(indent) {
+		self.WriteByte('\t')
+	}
+	if label != `` {
+		self.WriteString(label)
+		self.WriteByte(' ')
+	}
+	self.WriteString(typ.String())
+	self.WriteString(` [`)
+	self.WriteString(self.vis(typ, field, depth).String())
+	self.WriteString(`]`)
+
+	if private {
+		self.WriteString(` private`)
+	}
+	if value {
+		self.WriteString(` value`)
+	}
+	if mark := explainMark(wal); mark != `` {
+		self.WriteByte(' ')
+		self.WriteString(mark)
+	}
+
+	indent++
+
+	switch wal := wal.(type) {
+	case ptrWalker:
+		self.node(indent, depth, `*`, typ.Elem(), field, wal[0], false)
+	case ptrOnceWalker:
+		self.node(indent, depth, `*`, typ.Elem(), field, wal[0], false)
+	case listWalker:
+		self.node(indent, depth+1, `[]`, typ.Elem(), field, wal[0], false)
+	case listModeWalker:
+		self.node(indent, depth+1, `[]`, typ.Elem(), field, wal.Inner, false)
+	case listParWalker:
+		self.node(indent, depth+1, `[]`, typ.Elem(), field, wal[0], false)
+	case structWalker:
+		self.fields(indent, depth+1, typ, wal)
+	case structModeWalker:
+		self.fields(indent, depth+1, typ, wal.Fields)
+	case mapWalker:
+		self.entries(indent, depth+1, typ, field, wal)
+	case mapModeWalker:
+		self.entries(indent, depth+1, typ, field, mapWalker(wal))
+	}
+}
+
+func (self *walkExplain) fields(indent, depth int, typ r.Type, wal structWalker) {
+	for _, val := range wal {
+		field := typ.Field(val.Index)
+		self.node(indent, depth, `.`+field.Name, field.Type, field, val.Inner, val.Private)
+	}
+}
+
+func (self *walkExplain) entries(
+	indent, depth int, typ r.Type, field r.StructField, wal mapWalker,
+) {
+	if wal.Key != nil {
+		self.node(indent, depth, `[key]`, typ.Key(), field, wal.Key, false)
+	}
+	if wal.Val != nil {
+		self.node(indent, depth, `[val]`, typ.Elem(), field, wal.Val, false)
+	}
+}
+
+func (self *walkExplain) vis(typ r.Type, field r.StructField, depth int) (_ vis) {
+	if self.Filter != nil {
+		return visitNode(self.Filter, typ, field, depth)
+	}
+	return
+}
+
+// Unwraps walkers which visit the node itself before walking its descendants.
+func explainInner(wal Walker) Walker {
+	switch wal := wal.(type) {
+	case valueWalker:
+		if wal.Desc != nil {
+			return explainInner(wal.Desc)
+		}
+		return explainInner(wal.Both)
+	case selfWalker:
+		return wal[0]
+	case selfFieldWalker:
+		return wal.Inner
+	case selfCtlWalker:
+		return wal.Inner
+	case selfLeaveWalker:
+		return wal.Inner
+	default:
+		return wal
+	}
+}
+
+func explainMark(wal Walker) string {
+	switch wal.(type) {
+	case *refWalker:
+		return `cycle`
+	case ifaceWalker:
+		return `dynamic`
+	case ptrOnceWalker:
+		return `once`
+	case listParWalker:
+		return `parallel`
+	default:
+		return ``
+	}
+}
+
+/**
+Reusing map iterators avoids an allocation per walked map. See the map
+benchmarks in tests.
+*/
+var mapIterPool = sync.Pool{New: func() any { return new(r.MapIter) }}
+
+func getMapIter(val r.Value) *r.MapIter {
+	iter := mapIterPool.Get().(*r.MapIter)
+	iter.Reset(val)
+	return iter
+}
+
+func putMapIter(iter *r.MapIter) {
+	iter.Reset(r.Value{})
+	mapIterPool.Put(iter)
+}
+
+/**
+Used in place of the user's visitor when walking with walkers generated for a
+non-zero `walkMode`. Implements `Visitor` by delegating to the user's visitor.
+Exactly one of `.Vis`, `.PathVis`, `.ErrVis` must be non-nil. `.LeaveVis` is
+used only together with `.Vis`.
+
+In `walkModePar`, the state is shared between goroutines, and only `.Sem` may
+be used for anything other than reading. Its capacity is the count of
+additional goroutines allowed for the walk.
+
+`.Skip` is set when the visitor returns `rf.SkipDesc`, and is immediately reset
+by `selfCtlWalker`. `.Done` is set when the visitor returns any other non-nil
+error, and is never reset.
+*/
+type walkState struct {
+	Vis      Visitor
+	PathVis  PathVisitor
+	ErrVis   VisitorErr
+	LeaveVis LeaveVisitor
+	Path     Path
+	Ptrs     map[walkPtr]struct{}
+	Sem      chan struct{}
+	Err      error
+	Skip     bool
+	Done     bool
+}
+
+/**
+Used by `rf.WalkPath`. Passing the state to walkers via an interface would
+otherwise allocate it on the heap, together with its path.
+*/
+var pathWalkStatePool = sync.Pool{New: func() any {
+	return &walkState{Path: make(Path, 0, expectedStructNesting)}
+}}
+
+func getPathWalkState(vis PathVisitor) *walkState {
+	state := pathWalkStatePool.Get().(*walkState)
+	state.PathVis = vis
+	return state
+}
+
+func putPathWalkState(state *walkState) {
+	*state = walkState{Path: state.Path[:0]}
+	pathWalkStatePool.Put(state)
+}
+
+/**
+Takes the state by value, which allows `walkWithState` to avoid a heap
+allocation of the state when walking in the default mode.
+*/
+func (self walkState) walk(wal Walker, val r.Value) { wal.Walk(val, &self) }
+
+func (self *walkState) Visit(val r.Value, field r.StructField) {
+	if self.PathVis != nil {
+		self.PathVis.VisitPath(val, field, self.Path)
+	} else if self.ErrVis != nil {
+		self.setErr(self.ErrVis.VisitErr(val, field))
+	} else {
+		self.Vis.Visit(val, field)
+	}
+}
+
+func (self *walkState) Leave(val r.Value, field r.StructField) {
+	self.LeaveVis.Leave(val, field)
+}
+
+// Non-blocking. True if another goroutine may be started.
+func (self *walkState) acquire() bool {
+	select {
+	case self.Sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (self *walkState) release() { <-self.Sem }
+
+func (self *walkState) setErr(err error) {
+	self.Skip = err == SkipDesc
+	if err == nil || self.Skip {
+		return
+	}
+
+	self.Done = true
+	if err != SkipAll {
+		self.Err = err
+	}
+}
+
+/**
+The type is included because a pointer to a struct and a pointer to its first
+field have the same address, but are walked differently.
+*/
+type walkPtr struct {
+	Ptr  u.Pointer
+	Type r.Type
+}
+
+// True if the pointer was not previously visited.
+func (self *walkState) addPtr(val r.Value) bool {
+	key := walkPtr{val.UnsafePointer(), val.Type()}
+
+	_, ok := self.Ptrs[key]
+	if ok {
+		return false
+	}
+
+	if self.Ptrs == nil {
+		self.Ptrs = map[walkPtr]struct{}{}
+	}
+	self.Ptrs[key] = struct{}{}
+	return true
+}
+
+/**
+Returns the given private field as an exported and addressable value, via
+`reflect.NewAt`. "reflect" doesn't allow to get an address of a field of a
+non-addressable struct, so in that case we copy the struct first.
+*/
+func privateField(val r.Value, index int) r.Value {
+	if !val.CanAddr() {
+		tar := r.New(val.Type()).Elem()
+		tar.Set(val)
+		val = tar
+	}
+
+	field := val.Field(index)
+	return r.NewAt(field.Type(), u.Pointer(field.UnsafeAddr())).Elem()
+}
+
+/**
+Calls `rf.DepthFilter.VisitDepth` when the filter implements it and the depth
+is known, and `rf.Filter.Visit` otherwise. Filter combinators call this with a
+negative depth from their `.Visit` methods, where the depth is unknown.
+*/
+func visitDepth(fil Filter, typ r.Type, field r.StructField, depth int) byte {
+	if depth >= 0 {
+		impl, _ := fil.(DepthFilter)
+		if impl != nil {
+			return impl.VisitDepth(typ, field, depth)
+		}
+	}
+	return fil.Visit(typ, field)
+}
+
+/**
+Implemented by filter combinators, which implement `rf.DepthFilter` only for
+the sake of their inner filters. Walkers track depth only for filters which
+actually use it, see `walkRef.depth`.
+*/
+type depthUser interface{ usesDepth() bool }
+
+// True for kinds whose descendants have the same depth. See `rf.DepthFilter`.
+func isKindDeref(typ r.Type) bool {
+	if typ == nil {
+		return false
+	}
+	kind := typ.Kind()
+	return kind == r.Ptr || kind == r.Interface
+}
+
+func usesDepth(fil Filter) bool {
+	impl, _ := fil.(depthUser)
+	if impl != nil {
+		return impl.usesDepth()
+	}
+	_, ok := fil.(DepthFilter)
+	return ok
+}
+
+func usesDepthAny(src []Filter) bool {
+	for _, val := range src {
+		if usesDepth(val) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+Result of the given filter for the given node during a walk. Combines the
+static result with the result of `rf.ValueFilter.VisitValue`, if implemented.
+Used by filter combinators, whose inner filters may mix value filters with
+static filters. The depth is negative when unknown, like for `visitDepth`.
+*/
+func visitValue(fil Filter, val r.Value, field r.StructField, depth int) byte {
+	impl, _ := fil.(valueUser)
+	if impl != nil {
+		return impl.visitValue(val, field, depth)
+	}
+
+	out := visitDepth(fil, val.Type(), field, depth)
+	if impl, _ := fil.(ValueFilter); impl != nil {
+		out &= impl.VisitValue(val, field)
+	}
+	return out
+}
+
+/**
+Implemented by filter combinators, which implement `rf.ValueFilter` only for
+the sake of their inner filters. Walkers consult filters for each value only
+when they actually use values. Unlike `rf.ValueFilter.VisitValue`, the result
+of `.visitValue` already includes the static result.
+*/
+type valueUser interface {
+	usesValue() bool
+	visitValue(val r.Value, field r.StructField, depth int) byte
+}
+
+func usesValue(fil Filter) bool {
+	impl, _ := fil.(valueUser)
+	if impl != nil {
+		return impl.usesValue()
+	}
+	_, ok := fil.(ValueFilter)
+	return ok
+}
+
+func usesValueAny(src []Filter) bool {
+	for _, val := range src {
+		if usesValue(val) {
+			return true
+		}
+	}
+	return false
+}
+
+func visitPrivate(fil Filter, typ r.Type, field r.StructField) bool {
+	impl, _ := fil.(PrivateFilter)
+	return impl != nil && impl.VisitPrivate(typ, field)
+}
+
+func visitPrivateAny(src []Filter, typ r.Type, field r.StructField) bool {
+	for _, val := range src {
+		if visitPrivate(val, typ, field) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+Combines the results of `visitDepth` and `visitMap`. The map flags are taken
+only from `rf.MapFilter`, and only for map types allowed by `rf.VisDesc`.
+*/
+func visitNode(fil Filter, typ r.Type, field r.StructField, depth int) vis {
+	out := vis(visitDepth(fil, typ, field, depth)) &^ VisMap
+	if typ.Kind() == r.Map && out.desc() {
+		out |= vis(visitMap(fil, typ, field) & VisMap)
+	}
+	return out
+}
+
+func visitMap(fil Filter, typ r.Type, field r.StructField) byte {
+	impl, _ := fil.(MapFilter)
+	if impl != nil {
+		return impl.VisitMap(typ, field)
+	}
+	return VisNone
+}
+
+func visitMapAny(src []Filter, typ r.Type, field r.StructField) (out byte) {
+	for _, val := range src {
+		out |= visitMap(val, typ, field)
+	}
+	return
+}
+
+func validateFilter(src Filter) {
+	validateFilterValue(src, r.ValueOf(src))
+}
+
+func validateFilterValue(src Filter, val r.Value) {
+	switch val.Kind() {
+	case r.Chan, r.Func, r.Map, r.Ptr, r.Slice, r.UnsafePointer:
+		panic(errInvalidFilter(src, val))
+	case r.Array:
+		validateFilterArray(src, val)
+	case r.Struct:
+		// Filters in interned lists are validated by `internFilters`.
+		if val.Type() != typeFilterList {
+			validateFilterStruct(src, val)
+		}
+	case r.Interface:
+		switch val.Type() {
+		case typeType:
+		case typeFilter:
+			if !val.IsNil() {
+				validateFilterValue(src, val.Elem())
+			}
+		default:
+			panic(errInvalidFilter(src, val))
+		}
+	}
+}
+
+func validateFilterArray(src Filter, val r.Value) {
+	for ind := range Iter(val.Len()) {
+		validateFilterValue(src, val.Index(ind))
+	}
+}
+
+func validateFilterStruct(src Filter, val r.Value) {
+	for ind := range Iter(val.NumField()) {
+		validateFilterValue(src, val.Field(ind))
+	}
+}
+
+func errInvalidFilter(src Filter, val r.Value) Err {
+	return Err{
+		`validating walk filter`,
+		fmt.Errorf(`invalid filter %#v: contains %v of kind %v`, src, val, val.Kind()),
+	}
+}
+
+// False if the non-nil filters don't fit into the capacity of the output.
+func maybeCombineFilters(src, out []Filter) ([]Filter, bool) {
+	for _, val := range src {
+		if val == nil {
+			continue
+		}
+
+		if len(out) >= cap(out) {
+			return nil, false
+		}
+		out = append(out, val)
+	}
+	return out, true
+}
+
+/**
+Interned list of filters, used by `rf.AndList` and `rf.OrList`. The zero value
+represents an empty list.
+
+Lists are interned one filter at a time via `unique.Make`: each list is
+identified by the list without its last filter, and the last filter. This makes
+lists comparable without serializing filters, ensures that equal lists have
+equal handles, and allows the runtime to reclaim lists which are no longer
+referenced. Reading a list requires neither locks nor allocations, see `.all`.
+*/
+type filterList struct{ unique.Handle[filterListNode] }
+
+type filterListNode struct {
+	Prev filterList
+	Last Filter
+	Len  int
+}
+
+func (self filterList) isEmpty() bool { return self == filterList{} }
+
+func (self filterList) len() int {
+	if self.isEmpty() {
+		return 0
+	}
+	return self.Value().Len
+}
+
+/**
+Iterates the filters from last to first. Combinators which use this combine
+the results via `&` or `|`, where the order doesn't matter.
+*/
+func (self filterList) all(yield func(Filter) bool) {
+	for !self.isEmpty() {
+		node := self.Value()
+		if !yield(node.Last) {
+			return
+		}
+		self = node.Prev
+	}
+}
+
+// Allocates a new slice of the filters in the original order.
+func (self filterList) slice() []Filter {
+	if self.isEmpty() {
+		return nil
+	}
+
+	out := make([]Filter, self.len())
+	ind := len(out)
+	for val := range self.all {
+		ind--
+		out[ind] = val
+	}
+	return out
+}
+
+func (self filterList) usesDepth() bool {
+	for val := range self.all {
+		if usesDepth(val) {
+			return true
+		}
+	}
+	return false
+}
+
+func (self filterList) usesValue() bool {
+	for val := range self.all {
+		if usesValue(val) {
+			return true
+		}
+	}
+	return false
+}
+
+func (self filterList) visitPrivate(typ r.Type, field r.StructField) bool {
+	for val := range self.all {
+		if visitPrivate(val, typ, field) {
+			return true
+		}
+	}
+	return false
+}
+
+func (self filterList) visitMap(typ r.Type, field r.StructField) (out byte) {
+	for val := range self.all {
+		out |= visitMap(val, typ, field)
+	}
+	return
+}
+
+func internFilters(src []Filter) (out filterList) {
+	for _, val := range src {
+		if val != nil {
+			validateFilter(val)
+			out = filterList{unique.Make(filterListNode{out, val, out.len() + 1})}
+		}
+	}
+	return
+}
+
+// Options are the comma-separated parts of the tag following the identifier.
+func tagHasOpt(tag, opt string) bool {
+	index := strings.IndexByte(tag, ',')
+	if index < 0 {
+		return false
+	}
+	tag = tag[index+1:]
+
+	for tag != `` {
+		var head string
+		index = strings.IndexByte(tag, ',')
+		if index >= 0 {
+			head, tag = tag[:index], tag[index+1:]
+		} else {
+			head, tag = tag, ``
+		}
+		if head == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// See `rf.PkgPathGlobFilter`. Empty package paths never match.
+func pkgPathMatch(pattern, pkg string) bool {
+	if pkg == `` {
+		return false
+	}
+	if pattern == `...` {
+		return true
+	}
+
+	base := strings.TrimSuffix(pattern, `/...`)
+	if base != pattern {
+		if pkgPathMatch(base, pkg) {
+			return true
+		}
+
+		// Match each ancestor of the package path against the base pattern.
+		for ind := range pkg {
+			if pkg[ind] == '/' && pkgPathMatch(base, pkg[:ind]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	match, _ := path.Match(pattern, pkg)
+	return match
+}
+
+func ifaceVisit(visTyp, ifaceTyp r.Type, hit byte) byte {
+	if visTyp == nil || ifaceTyp == nil {
+		return VisNone
 	}
 	if r.PtrTo(visTyp).Implements(ifaceTyp) {
 		return hit
 	}
 	return VisDesc
 }
+
+/**
+Comparison plan used by `rf.Diff`, the counterpart of a walker. Structural
+plans have the kind of the compared type and compare descendants. Leaf plans
+compare values as a whole. This includes maps whose keys or values have plans,
+because map keys can't be represented in paths. A plan with neither is empty
+and compares nothing. Empty plans exist only when referenced by descendants of
+cyclic types before the plan is finished; otherwise irrelevant nodes have nil
+plans. Interface plans refer to the cache for getting the plans of dynamic
+types, like `ifaceWalker`.
+*/
+type diffPlan struct {
+	Kind   r.Kind
+	Leaf   bool
+	Elem   *diffPlan
+	Fields []diffField
+	Ref    filterRef
+	Cache  *WalkerCache
+}
+
+type diffField struct {
+	Index   int
+	Private bool
+	Plan    *diffPlan
+}
+
+func (self *WalkerCache) getDiffPlan(ref walkRef) *diffPlan {
+	if ref.Type == nil {
+		return nil
+	}
+	return self.diffs.get(ref, self.Cap, self.makeDiffPlan)
+}
+
+func (self *WalkerCache) makeDiffPlan(ref walkRef) *diffPlan {
+	bui := diffBui{Cache: self}
+	return bui.plan(ref)
+}
+
+/**
+Builds plans like `walkBui` builds walkers, with the same derivation of types,
+fields and depths. Cyclic types are handled by referencing pending plans.
+*/
+type diffBui struct {
+	Cache   *WalkerCache
+	Pending map[walkRef]*diffPlan
+}
+
+func (self *diffBui) plan(ref walkRef) *diffPlan {
+	if ref.Type == nil || ref.Filter == nil {
+		return nil
+	}
+
+	out := self.Pending[ref]
+	if out != nil {
+		return out
+	}
+
+	vis := ref.vis()
+	if vis.desc() {
+		switch ref.Type.Kind() {
+		case r.Ptr, r.Array, r.Slice, r.Struct:
+			if self.Pending == nil {
+				self.Pending = map[walkRef]*diffPlan{}
+			}
+
+			out = new(diffPlan)
+			self.Pending[ref] = out
+			self.planDesc(ref, out)
+			delete(self.Pending, ref)
+
+			if out.Kind != r.Invalid {
+				return out
+			}
+
+		case r.Interface:
+			return &diffPlan{Kind: r.Interface, Ref: ref.filterRef, Cache: self.Cache}
+
+		case r.Map:
+			if self.plan(ref.key()) != nil || self.plan(ref.elemDeep()) != nil {
+				return &diffPlan{Leaf: true}
+			}
+		}
+	}
+
+	if vis.self() {
+		if out == nil {
+			out = new(diffPlan)
+		}
+		out.Leaf = true
+		return out
+	}
+	return nil
+}
+
+func (self *diffBui) planDesc(ref walkRef, out *diffPlan) {
+	depth := ref.depth()
+
+	switch ref.Type.Kind() {
+	case r.Ptr:
+		sub := ref
+		sub.Type = ref.Type.Elem()
+		out.Elem = self.plan(sub)
+		if out.Elem != nil {
+			out.Kind = r.Ptr
+		}
+
+	case r.Array, r.Slice:
+		sub := ref
+		sub.Type = ref.Type.Elem()
+		sub.Depth = depth
+		out.Elem = self.plan(sub)
+		if out.Elem != nil {
+			out.Kind = ref.Type.Kind()
+		}
+
+	case r.Struct:
+		for ind := range Iter(ref.Type.NumField()) {
+			field := ref.Type.Field(ind)
+			private := !IsFieldPublic(field)
+			if private && !visitPrivate(ref.Filter, field.Type, field) {
+				continue
+			}
+
+			sub := ref
+			sub.Parent = ref.Type
+			sub.Index = ind
+			sub.Type = field.Type
+			sub.Depth = depth
+
+			plan := self.plan(sub)
+			if plan != nil {
+				out.Fields = append(out.Fields, diffField{ind, private, plan})
+			}
+		}
+		if len(out.Fields) > 0 {
+			out.Kind = r.Struct
+		}
+	}
+}
+
+// Accumulates the output of `rf.Diff`.
+type differ struct {
+	Path Path
+	Out  []Change
+}
+
+func (self *differ) diff(plan *diffPlan, valA, valB r.Value) {
+	if plan == nil {
+		return
+	}
+
+	switch plan.Kind {
+	case r.Ptr:
+		self.diffPtr(plan, valA, valB)
+	case r.Array, r.Slice:
+		self.diffList(plan, valA, valB)
+	case r.Struct:
+		self.diffStruct(plan, valA, valB)
+	case r.Interface:
+		self.diffIface(plan, valA, valB)
+	default:
+		if plan.Leaf && !valueEqual(valA, valB) {
+			self.change(valA, valB)
+		}
+	}
+}
+
+func (self *differ) diffPtr(plan *diffPlan, valA, valB r.Value) {
+	nilA, nilB := valA.IsNil(), valB.IsNil()
+	if nilA != nilB {
+		self.change(valA, valB)
+		return
+	}
+	if nilA || valA.Pointer() == valB.Pointer() {
+		return
+	}
+	self.diff(plan.Elem, valA.Elem(), valB.Elem())
+}
+
+func (self *differ) diffList(plan *diffPlan, valA, valB r.Value) {
+	lenA, lenB := valA.Len(), valB.Len()
+
+	for ind := range Iter(max(lenA, lenB)) {
+		self.Path = append(self.Path, ind)
+		if ind >= lenA {
+			self.change(r.Value{}, valB.Index(ind))
+		} else if ind >= lenB {
+			self.change(valA.Index(ind), r.Value{})
+		} else {
+			self.diff(plan.Elem, valA.Index(ind), valB.Index(ind))
+		}
+		self.Path = self.Path[:len(self.Path)-1]
+	}
+}
+
+func (self *differ) diffStruct(plan *diffPlan, valA, valB r.Value) {
+	for _, field := range plan.Fields {
+		self.Path = append(self.Path, field.Index)
+		if field.Private {
+			self.diff(field.Plan, privateField(valA, field.Index), privateField(valB, field.Index))
+		} else {
+			self.diff(field.Plan, valA.Field(field.Index), valB.Field(field.Index))
+		}
+		self.Path = self.Path[:len(self.Path)-1]
+	}
+}
+
+/**
+Like `ifaceWalker`, gets the plans for dynamic types on the fly, reusing the
+field and filter of the interface node. Differences in dynamic types are
+reported only when the filter is interested in either type.
+*/
+func (self *differ) diffIface(plan *diffPlan, valA, valB r.Value) {
+	elemA, elemB := valA.Elem(), valB.Elem()
+	typA, typB := ValueType(elemA), ValueType(elemB)
+	planA := plan.Cache.getDiffPlan(plan.Ref.walkRef(typA))
+
+	if typA == typB {
+		self.diff(planA, elemA, elemB)
+		return
+	}
+	if planA != nil || plan.Cache.getDiffPlan(plan.Ref.walkRef(typB)) != nil {
+		self.change(valA, valB)
+	}
+}
+
+func (self *differ) change(valA, valB r.Value) {
+	self.Out = append(self.Out, Change{self.Path.Copy(), valA, valB})
+}
+
+// Nil and empty maps are equal, like nil and empty slices in `differ`.
+func valueEqual(valA, valB r.Value) bool {
+	if valA.Kind() == r.Map && valA.Len() == 0 && valB.Len() == 0 {
+		return true
+	}
+	if valA.Comparable() && valB.Comparable() {
+		return valA.Equal(valB)
+	}
+	return r.DeepEqual(valA.Interface(), valB.Interface())
+}
+
+/**
+Copying plan used by `rf.CloneWith`. A nil plan means a shallow copy, which is
+the cheapest option and is used whenever a deep copy would make no difference.
+Struct plans list only the fields which are not copied shallowly. An empty
+plan, which may occur only for cyclic types, also means a shallow copy. Like
+`diffPlan`, interface plans refer to the cache.
+*/
+type clonePlan struct {
+	Kind   r.Kind
+	Zero   bool
+	Elem   *clonePlan
+	Fields []cloneField
+	Ref    filterRef
+	Cache  *WalkerCache
+}
+
+type cloneField struct {
+	Index   int
+	Private bool
+	Plan    *clonePlan
+}
+
+func (self *WalkerCache) getClonePlan(ref walkRef) *clonePlan {
+	if ref.Type == nil {
+		return nil
+	}
+	return self.clones.get(ref, self.Cap, self.makeClonePlan)
+}
+
+func (self *WalkerCache) makeClonePlan(ref walkRef) *clonePlan {
+	bui := cloneBui{Cache: self}
+	return bui.plan(ref)
+}
+
+// Counterpart of `diffBui`.
+type cloneBui struct {
+	Cache   *WalkerCache
+	Pending map[walkRef]*clonePlan
+}
+
+func (self *cloneBui) plan(ref walkRef) *clonePlan {
+	out := self.Pending[ref]
+	if out != nil {
+		return out
+	}
+
+	vis := ref.vis()
+	if !vis.desc() {
+		if vis.self() {
+			return nil
+		}
+		return &clonePlan{Zero: true}
+	}
+
+	switch ref.Type.Kind() {
+	case r.Ptr, r.Array, r.Slice, r.Struct, r.Map:
+		if self.Pending == nil {
+			self.Pending = map[walkRef]*clonePlan{}
+		}
+
+		out = new(clonePlan)
+		self.Pending[ref] = out
+		self.planDesc(ref, vis, out)
+		delete(self.Pending, ref)
+
+		if out.Kind == r.Invalid {
+			return nil
+		}
+		return out
+
+	case r.Interface:
+		return &clonePlan{Kind: r.Interface, Ref: ref.filterRef, Cache: self.Cache}
+
+	default:
+		return nil
+	}
+}
+
+func (self *cloneBui) planDesc(ref walkRef, vis vis, out *clonePlan) {
+	depth := ref.depth()
+
+	switch ref.Type.Kind() {
+	case r.Ptr:
+		sub := ref
+		sub.Type = ref.Type.Elem()
+		out.Elem = self.plan(sub)
+		out.Kind = r.Ptr
+
+	case r.Slice:
+		sub := ref
+		sub.Type = ref.Type.Elem()
+		sub.Depth = depth
+		out.Elem = self.plan(sub)
+		out.Kind = r.Slice
+
+	case r.Array:
+		sub := ref
+		sub.Type = ref.Type.Elem()
+		sub.Depth = depth
+		out.Elem = self.plan(sub)
+		if out.Elem != nil {
+			out.Kind = r.Array
+		}
+
+	case r.Map:
+		if vis.val() {
+			sub := ref
+			sub.Type = ref.Type.Elem()
+			sub.Depth = depth
+			out.Elem = self.plan(sub)
+		}
+		out.Kind = r.Map
+
+	case r.Struct:
+		for ind := range Iter(ref.Type.NumField()) {
+			field := ref.Type.Field(ind)
+			private := !IsFieldPublic(field)
+			if private && !visitPrivate(ref.Filter, field.Type, field) {
+				continue
+			}
+
+			sub := ref
+			sub.Parent = ref.Type
+			sub.Index = ind
+			sub.Type = field.Type
+			sub.Depth = depth
+
+			plan := self.plan(sub)
+			if plan != nil {
+				out.Fields = append(out.Fields, cloneField{ind, private, plan})
+			}
+		}
+		if len(out.Fields) > 0 {
+			out.Kind = r.Struct
+		}
+	}
+}
+
+func errCloneTypes(dst, src r.Type) Err {
+	return Err{
+		`cloning`,
+		fmt.Errorf(`expected pointers of the same type, got %v and %v`, dst, src),
+	}
+}
+
+// Copies the source to the destination, which must be settable.
+func clone(plan *clonePlan, dst, src r.Value) {
+	if plan == nil {
+		dst.Set(src)
+		return
+	}
+	if plan.Zero {
+		dst.SetZero()
+		return
+	}
+
+	switch plan.Kind {
+	case r.Ptr:
+		clonePtr(plan, dst, src)
+	case r.Slice:
+		cloneSlice(plan, dst, src)
+	case r.Array:
+		for ind := range Iter(src.Len()) {
+			clone(plan.Elem, dst.Index(ind), src.Index(ind))
+		}
+	case r.Map:
+		cloneMap(plan, dst, src)
+	case r.Struct:
+		cloneStruct(plan, dst, src)
+	case r.Interface:
+		cloneIface(plan, dst, src)
+	default:
+		dst.Set(src)
+	}
+}
+
+func clonePtr(plan *clonePlan, dst, src r.Value) {
+	if src.IsNil() {
+		dst.SetZero()
+		return
+	}
+
+	tar := r.New(src.Type().Elem())
+	clone(plan.Elem, tar.Elem(), src.Elem())
+	dst.Set(tar)
+}
+
+func cloneSlice(plan *clonePlan, dst, src r.Value) {
+	if src.IsNil() {
+		dst.SetZero()
+		return
+	}
+
+	tar := r.MakeSlice(src.Type(), src.Len(), src.Len())
+	if plan.Elem == nil {
+		r.Copy(tar, src)
+	} else {
+		for ind := range Iter(src.Len()) {
+			clone(plan.Elem, tar.Index(ind), src.Index(ind))
+		}
+	}
+	dst.Set(tar)
+}
+
+func cloneMap(plan *clonePlan, dst, src r.Value) {
+	if src.IsNil() {
+		dst.SetZero()
+		return
+	}
+
+	tar := r.MakeMapWithSize(src.Type(), src.Len())
+	iter := getMapIter(src)
+	defer putMapIter(iter)
+
+	var val r.Value
+	if plan.Elem != nil {
+		val = r.New(src.Type().Elem()).Elem()
+	}
+
+	for iter.Next() {
+		if plan.Elem == nil {
+			tar.SetMapIndex(iter.Key(), iter.Value())
+		} else {
+			clone(plan.Elem, val, iter.Value())
+			tar.SetMapIndex(iter.Key(), val)
+		}
+	}
+	dst.Set(tar)
+}
+
+func cloneStruct(plan *clonePlan, dst, src r.Value) {
+	dst.Set(src)
+
+	for _, field := range plan.Fields {
+		if field.Private {
+			clone(field.Plan, privateField(dst, field.Index), privateField(src, field.Index))
+		} else {
+			clone(field.Plan, dst.Field(field.Index), src.Field(field.Index))
+		}
+	}
+}
+
+// See `differ.diffIface`.
+func cloneIface(plan *clonePlan, dst, src r.Value) {
+	if src.IsNil() {
+		dst.SetZero()
+		return
+	}
+
+	elem := src.Elem()
+	sub := plan.Cache.getClonePlan(plan.Ref.walkRef(elem.Type()))
+	if sub == nil {
+		dst.Set(src)
+		return
+	}
+
+	tar := r.New(elem.Type()).Elem()
+	clone(sub, tar, elem)
+	dst.Set(tar)
+}
+
+/**
+Filter used by `rf.Redact`. Selected nodes are visited without walking their
+descendants. Map keys are never walked, because they can't be redacted in
+place. Used with `walkModeWrite`, which makes the contents of interfaces and
+maps settable.
+*/
+type redactFilter [1]Filter
+
+func (self redactFilter) Visit(typ r.Type, field r.StructField) byte {
+	return self.visit(typ, field, -1)
+}
+
+func (self redactFilter) VisitDepth(typ r.Type, field r.StructField, depth int) byte {
+	return self.visit(typ, field, depth)
+}
+
+func (self redactFilter) usesDepth() bool { return usesDepth(self[0]) }
+
+func (self redactFilter) VisitValue(val r.Value, field r.StructField) byte {
+	return self.visitValue(val, field, -1)
+}
+
+func (self redactFilter) usesValue() bool { return usesValue(self[0]) }
+
+func (self redactFilter) visitValue(val r.Value, field r.StructField, depth int) byte {
+	return redactVis(vis(visitValue(self[0], val, field, depth)))
+}
+
+func (self redactFilter) VisitPrivate(typ r.Type, field r.StructField) bool {
+	return visitPrivate(self[0], typ, field)
+}
+
+func (self redactFilter) VisitMap(typ r.Type, field r.StructField) byte {
+	return visitMap(self[0], typ, field) &^ VisKey
+}
+
+func (self redactFilter) visit(typ r.Type, field r.StructField, depth int) byte {
+	return redactVis(vis(visitDepth(self[0], typ, field, depth)))
+}
+
+// Converts the result of the inner filter of `redactFilter`.
+func redactVis(vis vis) byte {
+	if vis.self() {
+		return VisSelf
+	}
+	return byte(vis)
+}
+
+/**
+Visitor used by `rf.Redact`, which visits only the nodes selected by the
+filter. Visited values are always settable, because `rf.Redact` walks the
+target of a pointer in `walkModeWrite`.
+*/
+type redactor struct{}
+
+func (redactor) Visit(val r.Value, _ r.StructField) { redactValue(val) }
+
+/**
+Only pointer methods are used. A value method can't modify the value, and would
+leave the secret as-is, so such values are zeroed instead.
+*/
+func redactValue(val r.Value) {
+	if isRedactor(val.Type()) {
+		val.Addr().Interface().(Redactor).Redact()
+	} else {
+		val.SetZero()
+	}
+}
+
+func isRedactor(typ r.Type) bool {
+	return !typ.Implements(Type[Redactor]()) && r.PtrTo(typ).Implements(Type[Redactor]())
+}
+
+/**
+Filter used by `rf.Redacted` for deep-copying everything, including private
+fields which may be redacted by the given filter.
+*/
+type redactCloneFilter [1]Filter
+
+func (redactCloneFilter) Visit(r.Type, r.StructField) byte { return VisAll }
+
+func (redactCloneFilter) VisitMap(r.Type, r.StructField) byte { return VisMap }
+
+func (self redactCloneFilter) VisitPrivate(typ r.Type, field r.StructField) bool {
+	return visitPrivate(self[0], typ, field)
+}
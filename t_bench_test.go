@@ -2,9 +2,7 @@ package rf
 
 import (
 	r "reflect"
-	"sync"
 	"testing"
-	"unsafe"
 )
 
 // Causes the input to escape, allowing us to measure allocs.
@@ -28,6 +26,17 @@ func benchGetWalker() {
 	GetWalker(r.TypeOf(&testOuter), All{})
 }
 
+func BenchmarkGetWalker_parallel(b *testing.B) {
+	benchGetWalker()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			benchGetWalker()
+		}
+	})
+}
+
 func BenchmarkWalk(b *testing.B) {
 	benchWalk()
 	b.ResetTimer()
@@ -41,6 +50,32 @@ func benchWalk() {
 	Walk(r.ValueOf(&testOuter), All{}, Nop{})
 }
 
+func BenchmarkClone(b *testing.B) {
+	benchClone()
+	b.ResetTimer()
+
+	for range Iter(b.N) {
+		benchClone()
+	}
+}
+
+func benchClone() { _ = Clone(&testOuter) }
+
+func BenchmarkWalkPath(b *testing.B) {
+	benchWalkPath()
+	b.ResetTimer()
+
+	for range Iter(b.N) {
+		benchWalkPath()
+	}
+}
+
+func benchWalkPath() {
+	WalkPath(r.ValueOf(&testSlice), TypeFilter[string]{}, PathVisitorFunc(nopPathVis))
+}
+
+func nopPathVis(r.Value, r.StructField, Path) {}
+
 func Benchmark_range_slice_values(b *testing.B) {
 	for range Iter(b.N) {
 		benchRangeSliceValues(testSlice)
@@ -124,30 +159,6 @@ func benchMapIterPool() {
 	defer putMapIter(iter)
 }
 
-var mapIterPool = sync.Pool{New: func() any { return new(r.MapIter) }}
-
-func getMapIter(val r.Value) *r.MapIter {
-	iter := mapIterPool.Get().(*r.MapIter)
-	mapIterReset(iter, val)
-	return iter
-}
-
-func putMapIter(iter *r.MapIter) {
-	mapIterClear(iter)
-	mapIterPool.Put(iter)
-}
-
-// Workaround for the missing `(*r.MapIter).Reset` in Go 1.17.
-func mapIterClear(iter *r.MapIter) {
-	*iter = r.MapIter{}
-}
-
-// Workaround for the missing `(*r.MapIter).Reset` in Go 1.17.
-func mapIterReset(iter *r.MapIter, val r.Value) {
-	mapIterClear(iter)
-	*(*r.Value)(unsafe.Pointer(iter)) = val
-}
-
 // Kinda slow, but tolerable.
 func Benchmark_map_iter_range(b *testing.B) {
 	for range Iter(b.N) {
@@ -5,7 +5,9 @@ import (
 	r "reflect"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 	"unsafe"
 )
 
@@ -56,6 +58,165 @@ type CyclicByMap struct {
 	Value string
 }
 
+/*
+Long chain of self-referencing types. Each type refers to itself and to the
+next type. Used to verify that walker generation for such types is linear
+rather than exponential.
+*/
+type Chain00 struct {
+	Next *Chain01
+	Self *Chain00
+	Str  string
+}
+type Chain01 struct {
+	Next *Chain02
+	Self *Chain01
+	Str  string
+}
+type Chain02 struct {
+	Next *Chain03
+	Self *Chain02
+	Str  string
+}
+type Chain03 struct {
+	Next *Chain04
+	Self *Chain03
+	Str  string
+}
+type Chain04 struct {
+	Next *Chain05
+	Self *Chain04
+	Str  string
+}
+type Chain05 struct {
+	Next *Chain06
+	Self *Chain05
+	Str  string
+}
+type Chain06 struct {
+	Next *Chain07
+	Self *Chain06
+	Str  string
+}
+type Chain07 struct {
+	Next *Chain08
+	Self *Chain07
+	Str  string
+}
+type Chain08 struct {
+	Next *Chain09
+	Self *Chain08
+	Str  string
+}
+type Chain09 struct {
+	Next *Chain10
+	Self *Chain09
+	Str  string
+}
+type Chain10 struct {
+	Next *Chain11
+	Self *Chain10
+	Str  string
+}
+type Chain11 struct {
+	Next *Chain12
+	Self *Chain11
+	Str  string
+}
+type Chain12 struct {
+	Next *Chain13
+	Self *Chain12
+	Str  string
+}
+type Chain13 struct {
+	Next *Chain14
+	Self *Chain13
+	Str  string
+}
+type Chain14 struct {
+	Next *Chain15
+	Self *Chain14
+	Str  string
+}
+type Chain15 struct {
+	Next *Chain16
+	Self *Chain15
+	Str  string
+}
+type Chain16 struct {
+	Next *Chain17
+	Self *Chain16
+	Str  string
+}
+type Chain17 struct {
+	Next *Chain18
+	Self *Chain17
+	Str  string
+}
+type Chain18 struct {
+	Next *Chain19
+	Self *Chain18
+	Str  string
+}
+type Chain19 struct {
+	Next *Chain20
+	Self *Chain19
+	Str  string
+}
+type Chain20 struct {
+	Next *Chain21
+	Self *Chain20
+	Str  string
+}
+type Chain21 struct {
+	Next *Chain22
+	Self *Chain21
+	Str  string
+}
+type Chain22 struct {
+	Next *Chain23
+	Self *Chain22
+	Str  string
+}
+type Chain23 struct {
+	Next *Chain24
+	Self *Chain23
+	Str  string
+}
+type Chain24 struct {
+	Self *Chain24
+	Str  string
+}
+
+// Branching cyclic type. See `Test_walking_depth_branching`.
+type BinaryTree struct {
+	Left  *BinaryTree
+	Right *BinaryTree
+	Value string
+}
+
+// Cyclic type via a slice. See `Test_walking_depth_cyclic_unlimited`.
+type TreeNode struct {
+	Kids []*TreeNode
+	Name string
+}
+
+type CyclicMutual struct {
+	Inner []CyclicMutualInner
+	Value string
+}
+
+type CyclicMutualInner struct {
+	Inner *CyclicMutual
+	Value string
+}
+
+type ValueNode struct {
+	Active bool
+	Name   string
+	Next   *ValueNode
+}
+
 var testOuter = Outer{
 	Embed:      Embed{EmbedStr: `embed val`, EmbedNum: 10},
 	EmbedPtr:   &Embed{EmbedStr: `embed ptr val`, EmbedNum: 20},
@@ -200,8 +361,282 @@ var testSlice = func() (out []Outer) {
 	return
 }()
 
+// Contains duplicate, zero and indirect strings. See `TestTrawlUnique` and others.
+type TrawlSrc struct {
+	Strs  []string
+	Ptr   *string
+	Iface any
+	Nums  []int
+}
+
+func makeTrawlSrc() TrawlSrc {
+	return TrawlSrc{
+		Strs:  []string{`one`, ``, `two`, `one`},
+		Ptr:   stringPtr(`two`),
+		Iface: `three`,
+		Nums:  []int{0, 10},
+	}
+}
+
+// Covers each kind copied by `rf.CloneWith`. `.Ptr` and `.Arr[0]` share memory.
+type CloneSrc struct {
+	Str     string
+	Ptr     *string
+	Strs    []string
+	Arr     [2]*string
+	Dict    map[string][]string
+	Iface   any
+	Cache   []string
+	Secret  string
+	Time    time.Time
+	Node    *ValueNode
+	private []string
+}
+
+func makeCloneSrc() CloneSrc {
+	str := `two`
+	return CloneSrc{
+		Str:     `one`,
+		Ptr:     &str,
+		Strs:    []string{`three`},
+		Arr:     [2]*string{&str, nil},
+		Dict:    map[string][]string{`four`: {`five`}},
+		Iface:   []string{`six`},
+		Cache:   []string{`seven`},
+		Secret:  `eight`,
+		Time:    time.Now(),
+		Node:    &ValueNode{true, `nine`, &ValueNode{false, `ten`, nil}},
+		private: []string{`eleven`},
+	}
+}
+
+type Password string
+
+func (self *Password) Redact() {
+	if *self != `` {
+		*self = `***`
+	}
+}
+
+// Implements `rf.Redactor` via a value method, which can't modify the value.
+type ValuePassword string
+
+func (ValuePassword) Redact() {}
+
+type RedactCreds struct {
+	Login string
+	Token string `secret:"true"`
+	Pass  Password
+}
+
+type RedactSrc struct {
+	Creds    RedactCreds
+	Ptr      *RedactCreds
+	List     []RedactCreds
+	Iface    any
+	IfacePtr any
+	Dict     map[string]RedactCreds
+	Keys     []string `secret:"true"`
+	private  RedactCreds
+}
+
+func makeRedactSrc() RedactSrc {
+	creds := func(name string) RedactCreds {
+		return RedactCreds{name, name + `_token`, Password(name + `_pass`)}
+	}
+	ptr := creds(`two`)
+
+	return RedactSrc{
+		Creds:    creds(`one`),
+		Ptr:      &ptr,
+		List:     []RedactCreds{creds(`three`)},
+		Iface:    creds(`four`),
+		IfacePtr: &RedactCreds{Login: `five`, Token: `five_token`},
+		Dict:     map[string]RedactCreds{`six`: creds(`six`)},
+		Keys:     []string{`seven`},
+		private:  creds(`eight`),
+	}
+}
+
+func redactedCreds(name string) RedactCreds {
+	return RedactCreds{Login: name, Pass: `***`}
+}
+
 type PanicVis struct{}
 
 func (self PanicVis) Visit(r.Value, r.StructField) {
 	panic(fmt.Errorf(`unexpected call to %q`, funcName(self.Visit)))
 }
+
+// Visits only nodes at depth 1, descending anywhere.
+type DepthOneFilter struct{}
+
+func (DepthOneFilter) Visit(r.Type, r.StructField) byte { return VisDesc }
+
+func (DepthOneFilter) VisitDepth(_ r.Type, _ r.StructField, depth int) byte {
+	if depth == 1 {
+		return VisBoth
+	}
+	return VisDesc
+}
+
+type LeaveVis []string
+
+func (self *LeaveVis) Visit(val r.Value, _ r.StructField) {
+	*self = append(*self, `enter `+leaveVisName(val))
+}
+
+func (self *LeaveVis) Leave(val r.Value, _ r.StructField) {
+	*self = append(*self, `leave `+leaveVisName(val))
+}
+
+type CountVis struct{ Count int64 }
+
+func (self *CountVis) Visit(r.Value, r.StructField) { atomic.AddInt64(&self.Count, 1) }
+
+func (self *CountVis) IsConcurrent() bool { return true }
+
+type PanicConcurrentVis struct{}
+
+func (PanicConcurrentVis) Visit(val r.Value, _ r.StructField) {
+	if val.String() == `inner val` {
+		panic(`panic from visitor`)
+	}
+}
+
+func (PanicConcurrentVis) IsConcurrent() bool { return true }
+
+// Counts visits, and panics upon visiting the given value.
+type PanicLastVis struct {
+	Count int64
+	Last  int
+}
+
+func (self *PanicLastVis) Visit(val r.Value, _ r.StructField) {
+	atomic.AddInt64(&self.Count, 1)
+	if int(val.Int()) == self.Last {
+		panic(`panic from visitor`)
+	}
+}
+
+func (*PanicLastVis) IsConcurrent() bool { return true }
+
+type PrivateStrFilter struct{}
+
+func (PrivateStrFilter) Visit(typ r.Type, _ r.StructField) byte {
+	return TypeFilter[string]{}.Visit(typ, r.StructField{})
+}
+
+func (PrivateStrFilter) VisitPrivate(_ r.Type, field r.StructField) bool {
+	return field.Name == `private`
+}
+
+var slowFilterCalls int64
+
+// Slow filter for testing concurrent walker building.
+type SlowFilter struct{}
+
+func (SlowFilter) Visit(typ r.Type, _ r.StructField) byte {
+	atomic.AddInt64(&slowFilterCalls, 1)
+	time.Sleep(time.Millisecond)
+	return TypeFilter[string]{}.Visit(typ, r.StructField{})
+}
+
+// Filters must not contain pointers. This causes panics when building walkers.
+type InvalidFilter struct{ _ *int }
+
+func (InvalidFilter) Visit(r.Type, r.StructField) byte { return VisAll }
+
+type FieldVis []FieldVisit
+
+func (self *FieldVis) Visit(val r.Value, field r.StructField) {
+	*self = append(*self, FieldVisit{val.Interface(), field})
+}
+
+type FieldVisit struct {
+	Val   any
+	Field r.StructField
+}
+
+// Visits strings of at least `Min` bytes, skipping inactive nodes.
+type ActiveStrFilter struct{ Min int }
+
+func (ActiveStrFilter) Visit(typ r.Type, _ r.StructField) byte {
+	if typ == Type[string]() {
+		return VisSelf
+	}
+	return VisDesc
+}
+
+func (self ActiveStrFilter) VisitValue(val r.Value, _ r.StructField) byte {
+	switch val.Kind() {
+	case r.String:
+		if val.Len() >= self.Min {
+			return VisBoth
+		}
+		return VisNone
+	case r.Struct:
+		if val.Type() == Type[ValueNode]() && !val.Field(0).Bool() {
+			return VisNone
+		}
+	}
+	return VisBoth
+}
+
+type PtrStringer struct{ Str string }
+
+func (self *PtrStringer) String() string { return self.Str }
+
+type NamedStr string
+
+type NamedStrs []string
+
+type NamedInts []int
+
+// Selects strings at the given depth. See `rf.DepthFilter`.
+type DepthStrFilter int
+
+func (DepthStrFilter) Visit(r.Type, r.StructField) byte { return VisDesc }
+
+func (self DepthStrFilter) VisitDepth(typ r.Type, _ r.StructField, depth int) byte {
+	if depth == int(self) && typ == Type[string]() {
+		return VisBoth
+	}
+	return VisDesc
+}
+
+type DiffInner struct {
+	Name string
+	Tags []string
+}
+
+type DiffRec struct {
+	Id        int
+	Name      string
+	Inner     *DiffInner
+	List      []DiffInner
+	Meta      map[string]string
+	Any       any
+	UpdatedAt time.Time
+	private   string
+}
+
+type DiffOut struct {
+	Path Path
+	Old  any
+	New  any
+}
+
+func diffOut(src []Change) (out []DiffOut) {
+	iface := func(val r.Value) any {
+		if val.IsValid() {
+			return val.Interface()
+		}
+		return nil
+	}
+
+	for _, val := range src {
+		out = append(out, DiffOut{val.Path, iface(val.Old), iface(val.New)})
+	}
+	return
+}
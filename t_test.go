@@ -3,6 +3,10 @@ package rf
 import (
 	"fmt"
 	r "reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 	u "unsafe"
@@ -487,6 +491,199 @@ func TestTagFilter(t *testing.T) {
 	test(VisBoth, `db`, `two`, `json:"one" db:"two"`)
 }
 
+func TestTagPresentFilter(t *testing.T) {
+	test := func(exp byte, key string, tag r.StructTag) {
+		t.Helper()
+		eq(t, exp, TagPresentFilter(key).Visit(nil, r.StructField{Tag: tag}))
+	}
+
+	test(VisDesc, ``, ``)
+	test(VisDesc, ``, `json:""`)
+	test(VisDesc, `json`, ``)
+	test(VisDesc, `json`, `db:"one"`)
+	test(VisBoth, `json`, `json:""`)
+	test(VisBoth, `json`, `json:"-"`)
+	test(VisBoth, `json`, `json:"one" db:"two"`)
+	test(VisBoth, `db`, `json:"one" db:"two"`)
+}
+
+func TestTagIdentFilter(t *testing.T) {
+	test := func(exp byte, key, ident string, tag r.StructTag) {
+		t.Helper()
+		eq(t, exp, TagIdentFilter{key, ident}.Visit(nil, r.StructField{Tag: tag}))
+	}
+
+	test(VisDesc, ``, ``, ``)
+	test(VisDesc, `json`, ``, ``)
+	test(VisDesc, `json`, ``, `db:"one"`)
+	test(VisDesc, `json`, `one`, `json:"two"`)
+	test(VisDesc, `json`, `one`, `json:"-"`)
+	test(VisDesc, `json`, `one`, `db:"one"`)
+	test(VisBoth, `json`, ``, `json:""`)
+	test(VisBoth, `json`, ``, `json:"-"`)
+	test(VisBoth, `json`, ``, `json:",omitempty"`)
+	test(VisBoth, `json`, `one`, `json:"one"`)
+	test(VisBoth, `json`, `one`, `json:"one,omitempty"`)
+	test(VisBoth, `db`, `two`, `json:"one" db:"two,secret"`)
+}
+
+func TestTagOptFilter(t *testing.T) {
+	test := func(exp byte, key, opt string, tag r.StructTag) {
+		t.Helper()
+		eq(t, exp, TagOptFilter{key, opt}.Visit(nil, r.StructField{Tag: tag}))
+	}
+
+	test(VisDesc, ``, ``, ``)
+	test(VisDesc, `json`, ``, `json:"one,"`)
+	test(VisDesc, `json`, `omitempty`, ``)
+	test(VisDesc, `json`, `omitempty`, `json:"omitempty"`)
+	test(VisDesc, `json`, `omitempty`, `json:"one,omitemptyish"`)
+	test(VisDesc, `json`, `omitempty`, `db:"one,omitempty"`)
+	test(VisBoth, `json`, `omitempty`, `json:"one,omitempty"`)
+	test(VisBoth, `json`, `omitempty`, `json:",omitempty"`)
+	test(VisBoth, `json`, `omitempty`, `json:"one,string,omitempty"`)
+	test(VisBoth, `json`, `string`, `json:"one,string,omitempty"`)
+	test(VisBoth, `db`, `secret`, `json:"one" db:"two,secret"`)
+}
+
+func TestTagPrefixFilter(t *testing.T) {
+	test := func(exp byte, key, prefix string, tag r.StructTag) {
+		t.Helper()
+		eq(t, exp, TagPrefixFilter{key, prefix}.Visit(nil, r.StructField{Tag: tag}))
+	}
+
+	test(VisDesc, ``, ``, ``)
+	test(VisDesc, `json`, ``, ``)
+	test(VisDesc, `json`, `one`, `json:"two"`)
+	test(VisDesc, `json`, `one`, `db:"one"`)
+	test(VisBoth, `json`, ``, `json:""`)
+	test(VisBoth, `json`, `one`, `json:"one"`)
+	test(VisBoth, `json`, `one`, `json:"one_two,omitempty"`)
+}
+
+func TestTagGlobFilter(t *testing.T) {
+	test := func(exp byte, key, pattern string, tag r.StructTag) {
+		t.Helper()
+		eq(t, exp, TagGlobFilter{key, pattern}.Visit(nil, r.StructField{Tag: tag}))
+	}
+
+	test(VisDesc, ``, `*`, ``)
+	test(VisDesc, `db`, `*`, ``)
+	test(VisDesc, `db`, `*_id`, `db:"user_name"`)
+	test(VisDesc, `db`, `*_id`, `json:"user_id"`)
+	test(VisDesc, `db`, `[`, `db:"["`)
+	test(VisBoth, `db`, `*`, `db:""`)
+	test(VisBoth, `db`, `*_id`, `db:"user_id"`)
+	test(VisBoth, `db`, `user_?d`, `db:"user_id"`)
+	test(VisBoth, `db`, `user_id`, `db:"user_id"`)
+}
+
+func TestFieldNameFilter(t *testing.T) {
+	test := func(exp byte, name, field string) {
+		t.Helper()
+		eq(t, exp, FieldNameFilter(name).Visit(nil, r.StructField{Name: field}))
+	}
+
+	test(VisDesc, ``, ``)
+	test(VisDesc, `ID`, ``)
+	test(VisDesc, `ID`, `Id`)
+	test(VisDesc, `ID`, `UserID`)
+	test(VisBoth, `ID`, `ID`)
+}
+
+func TestFieldNameGlobFilter(t *testing.T) {
+	test := func(exp byte, pattern, field string) {
+		t.Helper()
+		eq(t, exp, FieldNameGlobFilter(pattern).Visit(nil, r.StructField{Name: field}))
+	}
+
+	test(VisDesc, ``, ``)
+	test(VisDesc, `*`, ``)
+	test(VisDesc, `*At`, `Attr`)
+	test(VisDesc, `[`, `[`)
+	test(VisBoth, `*`, `ID`)
+	test(VisBoth, `*At`, `CreatedAt`)
+	test(VisBoth, `*At`, `At`)
+	test(VisBoth, `ID`, `ID`)
+}
+
+func TestPkgPathFilter(t *testing.T) {
+	test := func(exp byte, pkg string, typ r.Type) {
+		t.Helper()
+		eq(t, exp, PkgPathFilter(pkg).Visit(typ, r.StructField{}))
+	}
+
+	test(VisDesc, ``, nil)
+	test(VisDesc, ``, Type[string]())
+	test(VisDesc, ``, Type[*Outer]())
+	test(VisDesc, `time`, nil)
+	test(VisDesc, `time`, Type[string]())
+	test(VisDesc, `time`, Type[*time.Time]())
+	test(VisDesc, `time`, Type[Outer]())
+	test(VisBoth, `time`, Type[time.Time]())
+	test(VisBoth, `time`, Type[time.Duration]())
+	test(VisBoth, `github.com/mitranim/rf`, Type[Outer]())
+}
+
+func TestPkgPathGlobFilter(t *testing.T) {
+	test := func(exp byte, pattern string, typ r.Type) {
+		t.Helper()
+		eq(t, exp, PkgPathGlobFilter(pattern).Visit(typ, r.StructField{}))
+	}
+
+	test(VisDesc, ``, nil)
+	test(VisDesc, `*`, nil)
+	test(VisDesc, `*`, Type[string]())
+	test(VisDesc, `*`, Type[Outer]())
+	test(VisDesc, `github.com/*`, Type[Outer]())
+	test(VisDesc, `github.com/mitranim/rf/...`, Type[time.Time]())
+	test(VisDesc, `github.com/mitranim/r/...`, Type[Outer]())
+	test(VisDesc, `...`, nil)
+	test(VisDesc, `...`, Type[string]())
+	test(VisDesc, `.../rf`, Type[Outer]())
+	test(VisBoth, `*`, Type[time.Time]())
+	test(VisBoth, `github.com/*/rf`, Type[Outer]())
+	test(VisBoth, `github.com/...`, Type[Outer]())
+	test(VisBoth, `github.com/mitranim/...`, Type[Outer]())
+	test(VisBoth, `github.com/mitranim/rf/...`, Type[Outer]())
+	test(VisBoth, `github.com/*/...`, Type[Outer]())
+	test(VisBoth, `...`, Type[Outer]())
+	test(VisBoth, `...`, Type[time.Time]())
+}
+
+func TestExcept(t *testing.T) {
+	test := func(exp byte, val Except) {
+		t.Helper()
+		eq(t, exp, val.Visit(nil, r.StructField{}))
+	}
+
+	test(VisAll, Except{})
+	test(VisAll, Except{Desc{}})
+	test(VisNone, Except{Self{}})
+	test(VisNone, Except{Both{}})
+	test(VisNone, Except{All{}})
+}
+
+func Test_walking_name_filters(t *testing.T) {
+	var tar Appender[string]
+	Walk(testOuterVal, And{tar.Filter(), Except{FieldNameGlobFilter(`Inner*`)}}, &tar)
+	eq(t, Appender[string]{`embed val`, `embed ptr val`, `outer val`, `outer iface`}, tar)
+
+	tar = nil
+	Walk(testOuterVal, And{tar.Filter(), Except{PkgPathGlobFilter(`github.com/mitranim/...`)}}, &tar)
+	eq(t, Appender[string](nil), tar)
+
+	tar = nil
+	Walk(testOuterVal, And{FieldNameFilter(`EmbedStr`), tar.Filter()}, &tar)
+	eq(t, Appender[string]{`embed val`, `embed ptr val`}, tar)
+}
+
+func Test_walking_tag_filters(t *testing.T) {
+	var tar Appender[string]
+	Walk(testOuterVal, And{TagGlobFilter{`db`, `*_str`}, TypeFilter[string]{}}, &tar)
+	eq(t, Appender[string]{`embed val`, `embed ptr val`, `outer val`, `inner val`, `inner ptr val`}, tar)
+}
+
 func TestIfaceFilter(t *testing.T) {
 	test := func(exp byte, visTyp r.Type, fil Filter) {
 		t.Helper()
@@ -500,6 +697,185 @@ func TestIfaceFilter(t *testing.T) {
 	test(VisDesc, Type[int](), IfaceFilter[fmt.Stringer]{})
 }
 
+func TestValueIfaceFilter(t *testing.T) {
+	test := func(exp byte, visTyp r.Type) {
+		t.Helper()
+		eq(t, exp, ValueIfaceFilter[fmt.Stringer]{}.Visit(visTyp, r.StructField{}))
+	}
+
+	test(VisNone, nil)
+	test(VisBoth, Type[time.Time]())
+	test(VisBoth, Type[*time.Time]())
+	test(VisBoth, Type[fmt.Stringer]())
+	test(VisBoth, Type[*PtrStringer]())
+	test(VisDesc, Type[PtrStringer]())
+	test(VisDesc, Type[string]())
+	test(VisDesc, Type[any]())
+
+	eq(t, byte(VisBoth), IfaceFilter[fmt.Stringer]{}.Visit(Type[PtrStringer](), r.StructField{}))
+}
+
+func TestAssignableFilter(t *testing.T) {
+	test := func(exp byte, visTyp r.Type, fil Filter) {
+		t.Helper()
+		eq(t, exp, fil.Visit(visTyp, r.StructField{}))
+	}
+
+	test(VisNone, nil, AssignableFilter[string]{})
+	test(VisBoth, Type[string](), AssignableFilter[string]{})
+	test(VisDesc, Type[NamedStr](), AssignableFilter[string]{})
+	test(VisDesc, Type[int](), AssignableFilter[string]{})
+
+	test(VisBoth, Type[[]string](), AssignableFilter[NamedStrs]{})
+	test(VisBoth, Type[NamedStrs](), AssignableFilter[[]string]{})
+	test(VisDesc, Type[NamedInts](), AssignableFilter[[]string]{})
+
+	test(VisBoth, Type[time.Time](), AssignableFilter[fmt.Stringer]{})
+	test(VisDesc, Type[PtrStringer](), AssignableFilter[fmt.Stringer]{})
+	test(VisBoth, Type[string](), AssignableFilter[any]{})
+}
+
+func TestConvertibleFilter(t *testing.T) {
+	test := func(exp byte, visTyp r.Type, fil Filter) {
+		t.Helper()
+		eq(t, exp, fil.Visit(visTyp, r.StructField{}))
+	}
+
+	test(VisNone, nil, ConvertibleFilter[string]{})
+	test(VisBoth, Type[string](), ConvertibleFilter[string]{})
+	test(VisBoth, Type[NamedStr](), ConvertibleFilter[string]{})
+	test(VisBoth, Type[int](), ConvertibleFilter[string]{})
+	test(VisDesc, Type[[]string](), ConvertibleFilter[string]{})
+	test(VisDesc, Type[Outer](), ConvertibleFilter[string]{})
+
+	fil := And{ConvertibleFilter[string]{}, KindFilter(r.String)}
+	test(VisBoth, Type[NamedStr](), fil)
+	test(VisDesc, Type[int](), fil)
+}
+
+func Test_walking_type_filters(t *testing.T) {
+	type Src struct {
+		Str   string
+		Named NamedStr
+		Num   int
+		Ptr   *PtrStringer
+		Val   PtrStringer
+	}
+
+	src := Src{`one`, `two`, 3, &PtrStringer{`four`}, PtrStringer{`five`}}
+
+	var strs []string
+	Walk(r.ValueOf(src), And{ConvertibleFilter[string]{}, KindFilter(r.String)}, VisitorFunc(func(val r.Value, _ r.StructField) {
+		strs = append(strs, val.String())
+	}))
+	eq(t, []string{`one`, `two`, `four`, `five`}, strs)
+
+	strs = nil
+	Walk(r.ValueOf(src), ValueIfaceFilter[fmt.Stringer]{}, VisitorFunc(func(val r.Value, _ r.StructField) {
+		strs = append(strs, val.Interface().(fmt.Stringer).String())
+	}))
+	eq(t, []string{`four`}, strs)
+
+	strs = nil
+	Walk(r.ValueOf(&src).Elem(), IfaceFilter[fmt.Stringer]{}, VisitorFunc(func(val r.Value, _ r.StructField) {
+		strs = append(strs, val.Addr().Interface().(fmt.Stringer).String())
+	}))
+	eq(t, []string{`four`, `five`}, strs)
+}
+
+func TestValueFilter(t *testing.T) {
+	src := &ValueNode{true, `one`, &ValueNode{false, `two`, &ValueNode{true, `three`, nil}}}
+
+	test := func(exp []string, fil Filter) {
+		t.Helper()
+		var tar Appender[string]
+		Walk(r.ValueOf(src), fil, &tar)
+		eq(t, exp, []string(tar))
+	}
+
+	test([]string{`one`, `two`, `three`}, TypeFilter[string]{})
+	test([]string{`one`}, ActiveStrFilter{})
+	test(nil, ActiveStrFilter{4})
+
+	src.Next.Active = true
+	test([]string{`one`, `two`, `three`}, ActiveStrFilter{})
+	test([]string{`three`}, ActiveStrFilter{4})
+
+	t.Run(`static_filters_unaffected`, func(t *testing.T) {
+		_, ok := GetWalker(Type[ValueNode](), TypeFilter[string]{}).(valueWalker)
+		eq(t, false, ok)
+
+		_, ok = GetWalker(Type[ValueNode](), ActiveStrFilter{}).(valueWalker)
+		eq(t, true, ok)
+	})
+
+	t.Run(`combinators`, func(t *testing.T) {
+		src.Next.Active = false
+
+		test([]string{`one`}, And{TypeFilter[string]{}, ActiveStrFilter{}})
+		test([]string{`one`}, AndOf(TypeFilter[string]{}, ActiveStrFilter{}))
+		test([]string{`one`}, AllowPrivate{ActiveStrFilter{}})
+		test([]string{`one`}, MaxDepth{ActiveStrFilter{}, 2})
+		test(nil, Or{ActiveStrFilter{4}})
+		test(nil, OrOf(ActiveStrFilter{4}))
+		test([]string{`one`, `two`, `three`}, Or{ActiveStrFilter{4}, FieldNameFilter(`Name`)})
+		test([]string{`one`}, And{ActiveStrFilter{}, Except{TypeFilter[int]{}}})
+
+		src.Next.Active = true
+		test([]string{`one`, `two`}, MaxDepth{ActiveStrFilter{}, 2})
+		test([]string{`three`}, Or{ActiveStrFilter{4}})
+
+		var out []string
+		TrawlWith(src, &out, ActiveStrFilter{4})
+		eq(t, []string{`three`}, out)
+
+		src.Next.Active = false
+		out = nil
+		TrawlWith(src, &out, ActiveStrFilter{})
+		eq(t, []string{`one`}, out)
+	})
+
+	t.Run(`self_walker`, func(t *testing.T) {
+		wal, _ := GetWalker(Type[ValueNode](), Or{ActiveStrFilter{}, TypeFilter[ValueNode]{}}).(valueWalker)
+		isNotNil(t, wal.Self)
+		_, ok := wal.Self.(valueWalker)
+		eq(t, false, ok)
+	})
+
+	t.Run(`modes`, func(t *testing.T) {
+		src.Next.Active = false
+
+		var paths []string
+		WalkPathFunc(r.ValueOf(src), ActiveStrFilter{}, func(val r.Value, _ r.StructField, path Path) {
+			paths = append(paths, fmt.Sprintf(`%v %v`, val.Interface(), path))
+		})
+		eq(t, []string{`one [1]`}, paths)
+
+		var strs []string
+		err := WalkFuncErr(r.ValueOf(src), ActiveStrFilter{}, func(val r.Value, _ r.StructField) error {
+			strs = append(strs, val.String())
+			return SkipAll
+		})
+		isNil(t, err)
+		eq(t, []string{`one`}, strs)
+	})
+}
+
+func TestExplain_ValueFilter(t *testing.T) {
+	eq(
+		t,
+		strings.Join([]string{
+			`rf.ValueNode [desc] value`,
+			`	.Name string [self] value`,
+			`	.Next *rf.ValueNode [desc] value`,
+			`		* rf.ValueNode [desc] value`,
+			`			.Name string [self] value`,
+			`			.Next *rf.ValueNode [desc] cycle`,
+		}, "\n"),
+		Explain(Type[ValueNode](), ActiveStrFilter{}),
+	)
+}
+
 func TestShallowIfaceFilter(t *testing.T) {
 	test := func(exp byte, visTyp r.Type, fil Filter) {
 		t.Helper()
@@ -522,157 +898,1751 @@ func TestAppender(t *testing.T) {
 	tar.Visit(r.ValueOf(`two`), r.StructField{})
 	tar.Visit(r.ValueOf(``), r.StructField{})
 
-	eq(t, Appender[string]{`one`, `two`}, tar)
-}
+	eq(t, Appender[string]{`one`, `two`}, tar)
+}
+
+func TestGetWalker_nil(t *testing.T) {
+	isNil(t, GetWalker(nil, nil))
+	isNil(t, GetWalker(r.TypeOf(``), nil))
+	isNil(t, GetWalker(nil, All{}))
+}
+
+func TestGetWalker_caching(t *testing.T) {
+	var filter0 TypeFilter[string]
+	var filter1 TypeFilter[int]
+	typeOuter := r.TypeOf(Outer{})
+	typeInner := r.TypeOf(Inner{})
+
+	is(t, GetWalker(typeOuter, filter0), GetWalker(typeOuter, filter0))
+	is(t, GetWalker(typeOuter, filter1), GetWalker(typeOuter, filter1))
+	is(t, GetWalker(typeInner, filter0), GetWalker(typeInner, filter0))
+	is(t, GetWalker(typeInner, filter1), GetWalker(typeInner, filter1))
+}
+
+func TestWalkerCache(t *testing.T) {
+	typeOuter := Type[Outer]()
+	typeInner := Type[Inner]()
+	filter := TypeFilter[string]{}
+
+	t.Run(`basic`, func(t *testing.T) {
+		var cache WalkerCache
+		eq(t, 0, cache.Len())
+		isNil(t, cache.GetWalker(nil, filter))
+		isNil(t, cache.GetWalker(typeOuter, nil))
+		eq(t, 0, cache.Len())
+
+		is(t, cache.GetWalker(typeOuter, filter), cache.GetWalker(typeOuter, filter))
+		eq(t, 1, cache.Len())
+
+		is(t, cache.GetWalker(typeInner, filter), cache.GetWalker(typeInner, filter))
+		eq(t, WalkerCacheStats{Len: 2, Builds: 2}, cache.Stats())
+
+		eq(t, 1, cache.Delete(typeInner))
+		eq(t, 0, cache.Delete(typeInner))
+		eq(t, 1, cache.Len())
+
+		cache.Clear()
+		eq(t, 0, cache.Len())
+		eq(t, WalkerCacheStats{Builds: 2}, cache.Stats())
+	})
+
+	t.Run(`separate from global`, func(t *testing.T) {
+		typ := r.StructOf([]r.StructField{{Name: `Str`, Type: Type[string]()}})
+
+		var ref walkRef
+		ref.Filter = filter
+		ref.Type = typ
+
+		var cache WalkerCache
+		isNotNil(t, cache.GetWalker(typ, filter))
+
+		_, ok := cache.walkers.got(ref)
+		eq(t, true, ok)
+
+		_, ok = walkerCacheStatic.walkers.got(ref)
+		eq(t, false, ok)
+	})
+
+	t.Run(`Walk`, func(t *testing.T) {
+		var cache WalkerCache
+		var tar Appender[string]
+
+		cache.Walk(testOuterVal, tar.Filter(), &tar)
+
+		eq(
+			t,
+			Appender[string]{`embed val`, `embed ptr val`, `outer val`, `inner val`, `inner ptr val`, `outer iface`},
+			tar,
+		)
+
+		// Walkers for dynamic values of interfaces are cached in the same cache.
+		eq(t, 1, cache.Delete(Type[string]()))
+	})
+
+	t.Run(`Walk after Clear`, func(t *testing.T) {
+		var cache WalkerCache
+		var tar Appender[string]
+
+		wal := cache.GetWalker(typeOuter, tar.Filter())
+		cache.Clear()
+		wal.Walk(testOuterVal, &tar)
+
+		eq(
+			t,
+			Appender[string]{`embed val`, `embed ptr val`, `outer val`, `inner val`, `inner ptr val`, `outer iface`},
+			tar,
+		)
+		eq(t, 1, cache.Len())
+	})
+
+	t.Run(`Cap`, func(t *testing.T) {
+		cache := WalkerCache{Cap: 2}
+
+		cache.GetWalker(Type[Outer](), filter)
+		cache.GetWalker(Type[Inner](), filter)
+		cache.GetWalker(Type[Embed](), filter)
+		cache.GetWalker(Type[Private](), filter)
+		cache.GetWalker(Type[Private](), filter)
+
+		eq(t, WalkerCacheStats{Len: 2, Builds: 4, Evictions: 2}, cache.Stats())
+	})
+}
+
+func TestWalkerCache_concurrent(t *testing.T) {
+	t.Run(`single build`, func(t *testing.T) {
+		const count = 16
+		var cache WalkerCache
+		var group sync.WaitGroup
+		var out [count]Walker
+
+		atomic.StoreInt64(&slowFilterCalls, 0)
+
+		for ind := range Iter(count) {
+			ind := ind
+			group.Add(1)
+			go func() {
+				defer group.Done()
+				out[ind] = cache.GetWalker(Type[Outer](), SlowFilter{})
+			}()
+		}
+		group.Wait()
+
+		for _, val := range out {
+			is(t, out[0], val)
+		}
+		eq(t, WalkerCacheStats{Len: 1, Builds: 1}, cache.Stats())
+
+		calls := atomic.LoadInt64(&slowFilterCalls)
+		isNotNil(t, cache.GetWalker(Type[Outer](), SlowFilter{}))
+		eq(t, calls, atomic.LoadInt64(&slowFilterCalls))
+	})
+
+	t.Run(`single plan build`, func(t *testing.T) {
+		const count = 16
+		var cache WalkerCache
+		var group sync.WaitGroup
+
+		for range Iter(count) {
+			group.Add(1)
+			go func() {
+				defer group.Done()
+				cache.Diff(testOuter, testOuter, SlowFilter{})
+
+				var out Outer
+				cache.CloneWith(&out, &testOuter, SlowFilter{})
+			}()
+		}
+		group.Wait()
+
+		// For each of `.Diff` and `.CloneWith`, plans for `Outer` and for the
+		// dynamic type of `.OuterIface`.
+		eq(t, WalkerCacheStats{Len: 4, Builds: 4}, cache.Stats())
+	})
+
+	t.Run(`panic`, func(t *testing.T) {
+		const count = 4
+		var cache WalkerCache
+		var group sync.WaitGroup
+		var out [count]any
+		fil := InvalidFilter{}
+
+		for ind := range Iter(count) {
+			ind := ind
+			group.Add(1)
+			go func() {
+				defer group.Done()
+				defer func() { out[ind] = recover() }()
+				cache.GetWalker(Type[Outer](), fil)
+			}()
+		}
+		group.Wait()
+
+		for _, val := range out {
+			isNotNil(t, val)
+		}
+
+		eq(t, 0, cache.Len())
+		panics(t, `invalid filter`, func() { cache.GetWalker(Type[Outer](), fil) })
+		eq(t, 0, cache.Len())
+	})
+}
+
+func Test_walking(t *testing.T) {
+	{
+		var tar Appender[string]
+
+		Walk(testOuterVal, tar.Filter(), &tar)
+
+		eq(
+			t,
+			Appender[string]{`embed val`, `embed ptr val`, `outer val`, `inner val`, `inner ptr val`, `outer iface`},
+			tar,
+		)
+	}
+
+	{
+		var tar Appender[int]
+
+		Walk(testOuterVal, tar.Filter(), &tar)
+
+		eq(
+			t,
+			Appender[int]{10, 20, 30, 40},
+			tar,
+		)
+	}
+}
+
+func Test_walking_maps(t *testing.T) {
+	test := func(exp Appender[string], src any, fil Filter) {
+		t.Helper()
+		var tar Appender[string]
+		Walk(r.ValueOf(src), fil, &tar)
+		sort.Strings(tar)
+		eq(t, exp, tar)
+	}
+
+	src := map[string]Inner{
+		`one`: {InnerStr: `one val`},
+		`two`: {InnerStr: `two val`},
+	}
+
+	test(nil, src, TypeFilter[string]{})
+	test(nil, src, Or{TypeFilter[string]{}, Maps(VisSelf | VisDesc)})
+	test(Appender[string]{`one`, `two`}, src, Or{TypeFilter[string]{}, Maps(VisKey)})
+	test(Appender[string]{`one val`, `two val`}, src, Or{TypeFilter[string]{}, Maps(VisVal)})
+	test(Appender[string]{`one`, `one val`, `two`, `two val`}, src, Or{TypeFilter[string]{}, Maps(VisMap)})
+	test(Appender[string]{`one`, `one val`, `two`, `two val`}, &src, Or{TypeFilter[string]{}, Maps(VisMap)})
+
+	test(
+		nil,
+		map[string]string(nil),
+		Or{TypeFilter[string]{}, Maps(VisMap)},
+	)
+
+	test(
+		Appender[string]{`one`, `two`},
+		map[int]any{10: `one`, 20: `two`},
+		Or{TypeFilter[string]{}, Maps(VisVal)},
+	)
+
+	{
+		var tar []string
+		vis := VisitorFunc(func(val r.Value, _ r.StructField) {
+			if val.Kind() == r.String {
+				tar = append(tar, val.String())
+			}
+		})
+
+		Walk(r.ValueOf(src), All{}, vis)
+		eq(t, []string(nil), tar)
+
+		Walk(r.ValueOf(src), AllMaps{}, vis)
+		sort.Strings(tar)
+		eq(t, []string{`one`, `one val`, `two`, `two val`}, tar)
+	}
+
+	{
+		var tar Appender[string]
+		Walk(testOuterVal, Or{tar.Filter(), Maps(VisVal)}, &tar)
+		eq(t, 6+len(testDict), len(tar))
+	}
+}
+
+func TestMaps(t *testing.T) {
+	test := func(exp byte, val Maps) {
+		t.Helper()
+		eq(t, byte(VisNone), val.Visit(nil, r.StructField{}))
+		eq(t, exp, val.VisitMap(nil, r.StructField{}))
+	}
+
+	test(VisNone, Maps(VisNone))
+	test(VisNone, Maps(VisBoth))
+	test(VisKey, Maps(VisKey))
+	test(VisVal, Maps(VisVal))
+	test(VisMap, Maps(VisMap))
+	test(VisMap, Maps(VisAll))
+}
+
+func Test_combinators_VisitMap(t *testing.T) {
+	test := func(exp byte, val MapFilter) {
+		t.Helper()
+		eq(t, exp, val.VisitMap(nil, r.StructField{}))
+	}
+
+	test(VisNone, And{All{}})
+	test(VisMap, And{All{}, AllMaps{}})
+	test(VisMap, AndOf(Self{}, Maps(VisKey), Maps(VisVal)))
+	test(VisVal, Or{Self{}, Maps(VisVal)})
+	test(VisKey, OrOf(Self{}, Maps(VisKey)))
+	test(VisKey, Not{Maps(VisKey)})
+	test(VisVal, InvertSelf{Maps(VisVal)})
+	test(VisMap, Xor{Maps(VisKey), Maps(VisVal)})
+	test(VisVal, AllowPrivate{Maps(VisVal)})
+	test(VisVal, MaxDepth{Maps(VisVal), 1})
+}
+
+func TestWalkPtr_invalid(t *testing.T) {
+	panics(t, `expected kind ptr`, func() {
+		WalkPtr(0, All{}, PanicVis{})
+	})
+	panics(t, `expected kind ptr`, func() {
+		WalkPtr(``, All{}, PanicVis{})
+	})
+	panics(t, `expected kind ptr`, func() {
+		WalkPtr(Outer{}, All{}, PanicVis{})
+	})
+}
+
+func TestWalkPtr_valid_nil(t *testing.T) {
+	WalkPtr(nil, All{}, PanicVis{})
+	WalkPtr((*Outer)(nil), All{}, PanicVis{})
+}
+
+func TestWalkPtr_valid_non_nil(t *testing.T) {
+	testWalkPtr(t, func(val any) {
+		WalkPtr(val, TypeFilter[string]{}, VisitorFunc(func(val r.Value, _ r.StructField) {
+			val.SetString(`val`)
+		}))
+	})
+}
+
+func TestWalkPtrFunc_invalid(t *testing.T) {
+	panics(t, `expected kind ptr`, func() {
+		WalkPtrFunc(0, All{}, func(r.Value, r.StructField) {})
+	})
+	panics(t, `expected kind ptr`, func() {
+		WalkPtrFunc(``, All{}, func(r.Value, r.StructField) {})
+	})
+	panics(t, `expected kind ptr`, func() {
+		WalkPtrFunc(Outer{}, All{}, func(r.Value, r.StructField) {})
+	})
+}
+
+func TestWalkPtrFunc_valid_nil(t *testing.T) {
+	WalkPtrFunc(nil, All{}, nil)
+	WalkPtrFunc((*Outer)(nil), All{}, nil)
+	WalkPtrFunc(nil, All{}, func(r.Value, r.StructField) {})
+	WalkPtrFunc((*Outer)(nil), All{}, func(r.Value, r.StructField) {})
+}
+
+func TestWalkPtrFunc_valid_non_nil(t *testing.T) {
+	testWalkPtr(t, func(val any) {
+		WalkPtrFunc(val, TypeFilter[string]{}, func(val r.Value, _ r.StructField) {
+			val.SetString(`val`)
+		})
+	})
+}
+
+func testWalkPtr(t testing.TB, fun func(any)) {
+	var tar Outer
+	fun(&tar)
+
+	var exp Outer
+	exp.Embed.EmbedStr = `val`
+	exp.OuterStr = `val`
+	exp.Inner.InnerStr = `val`
+
+	eq(t, exp, tar)
+}
+
+func Test_walking_cyclic_by_ptr(t *testing.T) {
+	type Type = CyclicByPtr
+
+	testGetWalkerCyclic[Type](t)
+
+	testWalkCyclic(
+		t,
+		Appender[string]{`inner_inner_val`, `inner_val`, `outer_val`},
+		TypeFilter[string]{},
+		&Type{
+			Inner: &Type{
+				Inner: &Type{Value: `inner_inner_val`},
+				Value: `inner_val`,
+			},
+			Value: `outer_val`,
+		},
+	)
+}
+
+func Test_walking_cyclic_by_slice(t *testing.T) {
+	type Type = CyclicBySlice
+
+	testGetWalkerCyclic[Type](t)
+
+	testWalkCyclic(
+		t,
+		Appender[string]{`inner_inner_val`, `inner_val_0`, `inner_val_1`, `outer_val`},
+		TypeFilter[string]{},
+		&Type{
+			Inner: []Type{
+				{
+					Inner: []Type{{Value: `inner_inner_val`}},
+					Value: `inner_val_0`,
+				},
+				{Value: `inner_val_1`},
+			},
+			Value: `outer_val`,
+		},
+	)
+}
+
+/**
+Unlike pointers and slices, maps are walked only when the filter sets the map
+flags, which `rf.TypeFilter` doesn't.
+*/
+func Test_walking_cyclic_by_map(t *testing.T) {
+	type Type = CyclicByMap
+
+	testGetWalkerCyclic[Type](t)
+
+	src := &Type{
+		Inner: map[string]CyclicByMap{`inner`: {
+			Inner: map[string]CyclicByMap{`inner`: {Value: `inner_inner_val`}},
+			Value: `inner_val`,
+		}},
+		Value: `outer_val`,
+	}
+
+	testWalkCyclic(
+		t,
+		Appender[string]{`outer_val`},
+		TypeFilter[string]{},
+		src,
+	)
+
+	testWalkCyclic(
+		t,
+		Appender[string]{`inner_inner_val`, `inner_val`, `outer_val`},
+		Or{TypeFilter[string]{}, Maps(VisVal)},
+		src,
+	)
+}
+
+func Test_walking_cyclic_mutual(t *testing.T) {
+	type Type = CyclicMutual
+
+	testGetWalkerCyclic[Type](t)
+
+	testWalkCyclic(
+		t,
+		Appender[string]{`inner_inner_val`, `inner_val`, `outer_val`},
+		TypeFilter[string]{},
+		&Type{
+			Inner: []CyclicMutualInner{{
+				Inner: &Type{Value: `inner_inner_val`},
+				Value: `inner_val`,
+			}},
+			Value: `outer_val`,
+		},
+	)
+
+	isNil(t, GetWalker(r.TypeOf(CyclicMutualInner{}), TypeFilter[int]{}))
+	isNotNil(t, GetWalker(r.TypeOf(CyclicMutualInner{}), TypeFilter[string]{}))
+}
+
+func Test_walking_cyclic_chain(t *testing.T) {
+	cache := new(WalkerCache)
+	isNotNil(t, cache.GetWalker(Type[Chain00](), TypeFilter[string]{}))
+	isNil(t, cache.GetWalker(Type[Chain00](), TypeFilter[int]{}))
+
+	src := Chain00{
+		Str:  `zero`,
+		Self: &Chain00{Str: `zero self`},
+		Next: &Chain01{
+			Str:  `one`,
+			Next: &Chain02{Str: `two`},
+		},
+	}
+
+	var tar Appender[string]
+	cache.Walk(r.ValueOf(src), TypeFilter[string]{}, &tar)
+	eq(t, Appender[string]{`two`, `one`, `zero self`, `zero`}, tar)
+}
+
+func deepField(typ r.Type, name string) r.StructField {
+	for _, field := range TypeDeepFields(typ) {
+		if field.Name == name {
+			return field
+		}
+	}
+	panic(fmt.Errorf(`missing field %q in %v`, name, typ))
+}
+
+func TestWalkFlat(t *testing.T) {
+	t.Run(`deep fields`, func(t *testing.T) {
+		var tar FieldVis
+		WalkFlat(r.ValueOf(&testOuter), TypeFilter[string]{}, &tar)
+
+		eq(
+			t,
+			FieldVis{
+				{`embed val`, deepField(Type[Outer](), `EmbedStr`)},
+				{`embed ptr val`, deepField(Type[Embed](), `EmbedStr`)},
+				{`outer val`, deepField(Type[Outer](), `OuterStr`)},
+				{`inner val`, deepField(Type[Inner](), `InnerStr`)},
+				{`inner ptr val`, deepField(Type[Inner](), `InnerStr`)},
+				{`outer iface`, deepField(Type[Outer](), `OuterIface`)},
+			},
+			tar,
+		)
+
+		eq(t, []int{0, 0}, tar[0].Field.Index)
+	})
+
+	t.Run(`embeds are not visited`, func(t *testing.T) {
+		var tar Appender[Embed]
+		WalkFlat(r.ValueOf(testOuter), tar.Filter(), &tar)
+		eq(t, Appender[Embed]{{`embed ptr val`, 20}}, tar)
+
+		tar = nil
+		Walk(r.ValueOf(testOuter), tar.Filter(), &tar)
+		eq(t, Appender[Embed]{{`embed val`, 10}, {`embed ptr val`, 20}}, tar)
+	})
+
+	t.Run(`private`, func(t *testing.T) {
+		src := Outer{
+			embedPrivate: Private{PrivateStr: `embed private val`},
+			private:      Private{PrivateStr: `private val`},
+		}
+
+		var tar Appender[string]
+		WalkFlat(r.ValueOf(&src), tar.Filter(), &tar)
+		eq(t, Appender[string](nil), tar)
+
+		WalkFlat(r.ValueOf(&src), AllowPrivate{tar.Filter()}, &tar)
+		eq(t, Appender[string]{`embed private val`, `private val`}, tar)
+	})
+
+	t.Run(`modify`, func(t *testing.T) {
+		tar := Outer{Embed: Embed{EmbedStr: `embed val`}}
+
+		WalkFlat(r.ValueOf(&tar), TypeFilter[string]{}, VisitorFunc(func(val r.Value, _ r.StructField) {
+			val.SetString(val.String() + ` modified`)
+		}))
+
+		eq(t, `embed val modified`, tar.Embed.EmbedStr)
+	})
+
+	t.Run(`leave`, func(t *testing.T) {
+		var tar LeaveVis
+		WalkFlat(r.ValueOf(testOuter), TypeFilter[Embed]{}, &tar)
+		eq(t, LeaveVis{`enter embed ptr val`, `leave embed ptr val`}, tar)
+	})
+}
+
+func TestMaxDepth(t *testing.T) {
+	test := func(exp byte, val MaxDepth, depth int) {
+		t.Helper()
+		eq(t, exp, val.VisitDepth(nil, r.StructField{}, depth))
+	}
+
+	test(VisNone, MaxDepth{}, 0)
+	test(VisNone, MaxDepth{All{}, -1}, 0)
+	test(VisAll&^VisDesc, MaxDepth{All{}, 0}, 0)
+	test(VisNone, MaxDepth{All{}, 0}, 1)
+	test(VisAll, MaxDepth{All{}, 2}, 1)
+	test(VisAll&^VisDesc, MaxDepth{All{}, 2}, 2)
+	test(VisSelf, MaxDepth{Self{}, 2}, 2)
+	test(VisNone, MaxDepth{Desc{}, 2}, 2)
+	test(VisNone, MaxDepth{All{}, 2}, 3)
+	test(VisNone, MaxDepth{MaxDepth{All{}, 1}, 2}, 2)
+	test(VisBoth, MaxDepth{DepthOneFilter{}, 2}, 1)
+	test(VisDesc, MaxDepth{DepthOneFilter{}, 2}, 0)
+
+	eq(t, byte(VisNone), MaxDepth{}.Visit(nil, r.StructField{}))
+	eq(t, byte(VisAll), MaxDepth{All{}, 0}.Visit(nil, r.StructField{}))
+	eq(t, true, MaxDepth{AllowPrivate{}, 0}.VisitPrivate(nil, r.StructField{}))
+}
+
+func Test_walking_depth(t *testing.T) {
+	test := func(exp Appender[string], fil Filter) {
+		t.Helper()
+
+		var tar Appender[string]
+		Walk(testOuterVal, fil, &tar)
+		eq(t, exp, tar)
+
+		tar = nil
+		Walk(r.ValueOf(&testOuter), fil, &tar)
+		eq(t, exp, tar)
+	}
+
+	test(nil, MaxDepth{TypeFilter[string]{}, 0})
+	test(Appender[string]{`outer val`, `outer iface`}, MaxDepth{TypeFilter[string]{}, 1})
+
+	test(
+		Appender[string]{`embed val`, `embed ptr val`, `outer val`, `inner val`, `inner ptr val`, `outer iface`},
+		MaxDepth{TypeFilter[string]{}, 2},
+	)
+
+	test(Appender[string]{`outer val`, `outer iface`}, And{DepthOneFilter{}, TypeFilter[string]{}})
+	test(Appender[string]{`outer val`, `outer iface`}, AndOf(DepthOneFilter{}, TypeFilter[string]{}))
+	test(Appender[string]{`outer val`}, And{Not{Not{DepthOneFilter{}}}, Except{FieldNameFilter(`OuterIface`)}, TypeFilter[string]{}})
+
+	fil := MaxDepth{TypeFilter[string]{}, 1}
+	is(t, GetWalker(Type[Outer](), fil), GetWalker(Type[Outer](), fil))
+
+	eq(
+		t,
+		strings.Join([]string{
+			`rf.Outer [desc]`,
+			`	.OuterStr string [self]`,
+			`	.OuterIface interface {} [self|desc] dynamic`,
+		}, "\n"),
+		Explain(Type[Outer](), MaxDepth{Or{TypeFilter[string]{}, TypeFilter[any]{}}, 1}),
+	)
+}
+
+func Test_walking_depth_cyclic(t *testing.T) {
+	src := CyclicByPtr{Value: `one`}
+	src.Inner = &CyclicByPtr{Value: `two`}
+	src.Inner.Inner = &CyclicByPtr{Value: `three`}
+	src.Inner.Inner.Inner = &CyclicByPtr{Value: `four`}
+
+	var tar Appender[string]
+	Walk(r.ValueOf(src), MaxDepth{TypeFilter[string]{}, 3}, &tar)
+	eq(t, Appender[string]{`three`, `two`, `one`}, tar)
+
+	tar = nil
+	Walk(r.ValueOf(src), TypeFilter[string]{}, &tar)
+	eq(t, Appender[string]{`four`, `three`, `two`, `one`}, tar)
+}
+
+func Test_walking_depth_cyclic_unlimited(t *testing.T) {
+	const msg = `exceeded the depth limit 1024 at type`
+
+	test := func(fil Filter) {
+		t.Helper()
+		panics(t, msg, func() { GetWalker(Type[TreeNode](), fil) })
+		panics(t, msg, func() { GetWalker(Type[TreeNode](), fil) })
+		panics(t, msg, func() { Diff(TreeNode{}, TreeNode{}, fil) })
+		panics(t, msg, func() { CloneWith(&TreeNode{}, &TreeNode{}, fil) })
+	}
+
+	test(Or{MaxDepth{FieldNameFilter(`Name`), 2}, TypeFilter[int]{}})
+	test(DepthOneFilter{})
+
+	fil := Or{MaxDepth{FieldNameFilter(`Name`), 2}, MaxDepth{TypeFilter[int]{}, 8}}
+	isNotNil(t, GetWalker(Type[TreeNode](), fil))
+}
+
+func Test_walking_depth_branching(t *testing.T) {
+	/**
+	Depth-aware walkers can't be reused for inner occurrences of cyclic types,
+	but are memoized per node and depth. Without that, this would take time
+	exponential in the depth.
+	*/
+	isNotNil(t, GetWalker(Type[BinaryTree](), MaxDepth{TypeFilter[string]{}, 64}))
+
+	src := BinaryTree{
+		Value: `one`,
+		Left: &BinaryTree{
+			Value: `two`,
+			Left:  &BinaryTree{Value: `three`},
+		},
+		Right: &BinaryTree{Value: `four`},
+	}
+
+	var tar Appender[string]
+	Walk(r.ValueOf(src), MaxDepth{TypeFilter[string]{}, 2}, &tar)
+	eq(t, Appender[string]{`two`, `four`, `one`}, tar)
+}
+
+func TestWalkOnce(t *testing.T) {
+	t.Run(`nil`, func(t *testing.T) {
+		WalkOnce(r.Value{}, All{}, PanicVis{})
+		WalkOnce(r.ValueOf(&CyclicByPtr{}), nil, PanicVis{})
+		WalkOnce(r.ValueOf(&CyclicByPtr{}), All{}, nil)
+	})
+
+	t.Run(`pointer cycle`, func(t *testing.T) {
+		src := &CyclicByPtr{Value: `outer_val`}
+		src.Inner = &CyclicByPtr{Inner: src, Value: `inner_val`}
+
+		var tar Appender[string]
+		WalkOnce(r.ValueOf(src), tar.Filter(), &tar)
+		eq(t, Appender[string]{`inner_val`, `outer_val`}, tar)
+	})
+
+	t.Run(`self cycle`, func(t *testing.T) {
+		src := &CyclicByPtr{Value: `val`}
+		src.Inner = src
+
+		var count int
+		WalkOnce(r.ValueOf(src), TypeFilter[*CyclicByPtr]{}, VisitorFunc(func(r.Value, r.StructField) {
+			count++
+		}))
+
+		// The outer pointer and the field pointing back to it.
+		eq(t, 2, count)
+	})
+
+	t.Run(`cycle through interface`, func(t *testing.T) {
+		type Type struct {
+			Inner any
+			Value string
+		}
+
+		src := &Type{Value: `outer_val`}
+		src.Inner = &Type{Inner: src, Value: `inner_val`}
+
+		var tar Appender[string]
+		WalkOnce(r.ValueOf(src), tar.Filter(), &tar)
+		eq(t, Appender[string]{`inner_val`, `outer_val`}, tar)
+	})
+
+	t.Run(`shared pointers`, func(t *testing.T) {
+		shared := &Inner{InnerStr: `shared`}
+		src := []*Inner{shared, {InnerStr: `unique`}, shared}
+
+		var tar Appender[string]
+		Walk(r.ValueOf(src), tar.Filter(), &tar)
+		eq(t, Appender[string]{`shared`, `unique`, `shared`}, tar)
+
+		tar = nil
+		WalkOnce(r.ValueOf(src), tar.Filter(), &tar)
+		eq(t, Appender[string]{`shared`, `unique`}, tar)
+	})
+
+	t.Run(`same address different types`, func(t *testing.T) {
+		src := &Inner{InnerStr: `val`}
+		pair := struct {
+			One *Inner
+			Two *string
+		}{src, &src.InnerStr}
+
+		var tar Appender[string]
+		WalkOnce(r.ValueOf(pair), tar.Filter(), &tar)
+		eq(t, Appender[string]{`val`, `val`}, tar)
+	})
+
+	t.Run(`separate walkers`, func(t *testing.T) {
+		typ := r.TypeOf((*CyclicByPtr)(nil))
+		fil := TypeFilter[string]{}
+
+		isNotNil(t, getWalker(typ, fil, walkModeOnce))
+		is(t, getWalker(typ, fil, walkModeOnce), getWalker(typ, fil, walkModeOnce))
+
+		if getWalker(typ, fil, walkModeOnce) == GetWalker(typ, fil) {
+			t.Fatalf(`expected walkers for different modes to be distinct`)
+		}
+	})
+}
+
+func TestWalkPath(t *testing.T) {
+	type Customer struct{ Email string }
+
+	type Order struct {
+		Id       string
+		Customer *Customer
+		Notes    []any
+	}
+
+	type Entry struct {
+		Val  any
+		Path Path
+	}
+
+	var tar []Entry
+
+	vis := func(val r.Value, _ r.StructField, path Path) {
+		tar = append(tar, Entry{val.Interface(), path.Copy()})
+	}
+
+	src := []Order{
+		{Id: `one`},
+		{Id: `two`, Customer: &Customer{`two@mail`}, Notes: []any{`note`, 10}},
+	}
+
+	WalkPathFunc(r.ValueOf(src), TypeFilter[string]{}, vis)
+
+	eq(
+		t,
+		[]Entry{
+			{`one`, Path{0, 0}},
+			{`two`, Path{1, 0}},
+			{`two@mail`, Path{1, 1, 0}},
+			{`note`, Path{1, 2, 0}},
+		},
+		tar,
+	)
+
+	tar = nil
+	WalkPathFunc(r.ValueOf(&src[1]), TypeFilter[*Customer]{}, vis)
+	eq(t, []Entry{{src[1].Customer, Path{1}}}, tar)
+
+	tar = nil
+	WalkPathFunc(r.ValueOf(`root`), TypeFilter[string]{}, vis)
+	eq(t, []Entry{{`root`, Path{}}}, tar)
+
+	WalkPath(r.ValueOf(src), All{}, nil)
+	WalkPathFunc(r.ValueOf(src), All{}, nil)
+
+	{
+		var count int
+		vis := PathVisitorFunc(func(_ r.Value, _ r.StructField, path Path) { count += len(path) })
+		val := r.ValueOf(&src)
+
+		eq(t, 0.0, testing.AllocsPerRun(100, func() { WalkPath(val, TypeFilter[string]{}, vis) }))
+		eq(t, true, count > 0)
+	}
+	WalkPathFunc(r.Value{}, All{}, func(r.Value, r.StructField, Path) { panic(`unreachable`) })
+}
+
+func TestWalkErr(t *testing.T) {
+	t.Run(`nil`, func(t *testing.T) {
+		eq(t, nil, WalkErr(testOuterVal, All{}, nil))
+		eq(t, nil, WalkFuncErr(testOuterVal, All{}, nil))
+		eq(t, nil, WalkFuncErr(testOuterVal, nil, func(r.Value, r.StructField) error {
+			panic(`unreachable`)
+		}))
+	})
+
+	t.Run(`continue`, func(t *testing.T) {
+		var tar Appender[string]
+		err := WalkFuncErr(testOuterVal, tar.Filter(), func(val r.Value, field r.StructField) error {
+			tar.Visit(val, field)
+			return nil
+		})
+		eq(t, nil, err)
+		eq(
+			t,
+			Appender[string]{`embed val`, `embed ptr val`, `outer val`, `inner val`, `inner ptr val`, `outer iface`},
+			tar,
+		)
+	})
+
+	t.Run(`stop`, func(t *testing.T) {
+		test := func(exp Appender[string], expErr, retErr error, stopAt string) {
+			t.Helper()
+
+			var tar Appender[string]
+			err := WalkFuncErr(
+				r.ValueOf(&testSlice),
+				Or{tar.Filter(), Maps(VisVal)},
+				func(val r.Value, field r.StructField) error {
+					tar.Visit(val, field)
+					if val.String() == stopAt {
+						return retErr
+					}
+					return nil
+				},
+			)
+
+			eq(t, expErr, err)
+			eq(t, exp, tar)
+		}
+
+		test(Appender[string]{`embed val`}, nil, SkipAll, `embed val`)
+		test(Appender[string]{`embed val`, `embed ptr val`, `outer val`}, nil, SkipAll, `outer val`)
+		test(Appender[string]{`embed val`, `embed ptr val`}, ErrStr(`fail`), ErrStr(`fail`), `embed ptr val`)
+	})
+
+	t.Run(`stop in map`, func(t *testing.T) {
+		var count int
+		err := WalkFuncErr(testDictVal, AllMaps{}, func(val r.Value, _ r.StructField) error {
+			if val.Kind() == r.String {
+				count++
+				return SkipAll
+			}
+			return nil
+		})
+		eq(t, nil, err)
+		eq(t, 1, count)
+	})
+
+	t.Run(`skip descendants`, func(t *testing.T) {
+		var tar Appender[string]
+		err := WalkFuncErr(testOuterVal, Both{}, func(val r.Value, field r.StructField) error {
+			if field.Name == `Inner` || field.Name == `InnerPtr` {
+				return SkipDesc
+			}
+			if val.Kind() == r.String {
+				tar.Visit(val, field)
+			}
+			return nil
+		})
+		eq(t, nil, err)
+		eq(t, Appender[string]{`embed val`, `embed ptr val`, `outer val`, `outer iface`}, tar)
+	})
+
+	t.Run(`skip leaf`, func(t *testing.T) {
+		var tar Appender[string]
+		err := WalkFuncErr(testOuterVal, TypeFilter[string]{}, func(val r.Value, field r.StructField) error {
+			tar.Visit(val, field)
+			return SkipDesc
+		})
+		eq(t, nil, err)
+		eq(
+			t,
+			Appender[string]{`embed val`, `embed ptr val`, `outer val`, `inner val`, `inner ptr val`, `outer iface`},
+			tar,
+		)
+	})
+}
+
+func leaveVisName(val r.Value) string {
+	if val.Kind() == r.Struct {
+		return val.Field(0).String()
+	}
+	return val.String()
+}
+
+func TestLeaveVisitor(t *testing.T) {
+	type Node struct {
+		Name string
+		Kids []*Node
+	}
+
+	src := &Node{
+		Name: `root`,
+		Kids: []*Node{
+			{Name: `one`, Kids: []*Node{{Name: `two`}}},
+			{Name: `three`},
+		},
+	}
+
+	{
+		var tar LeaveVis
+		WalkPtr(src, TypeFilter[Node]{}, &tar)
+
+		eq(
+			t,
+			LeaveVis{
+				`enter root`,
+				`enter one`,
+				`enter two`,
+				`leave two`,
+				`leave one`,
+				`enter three`,
+				`leave three`,
+				`leave root`,
+			},
+			tar,
+		)
+	}
+
+	{
+		var tar LeaveVis
+		WalkPtr(&src.Kids[0], Or{TypeFilter[Node]{}, TypeFilter[string]{}}, &tar)
+
+		eq(
+			t,
+			LeaveVis{
+				`enter one`,
+				`enter one`,
+				`leave one`,
+				`enter two`,
+				`enter two`,
+				`leave two`,
+				`leave two`,
+				`leave one`,
+			},
+			tar,
+		)
+	}
+
+	t.Run(`WalkOnce`, func(t *testing.T) {
+		src := &CyclicByPtr{Value: `val`}
+		src.Inner = src
+
+		var tar LeaveVis
+		WalkOnce(r.ValueOf(src), TypeFilter[string]{}, &tar)
+		eq(t, LeaveVis{`enter val`, `leave val`}, tar)
+	})
+
+	t.Run(`GetWalker`, func(t *testing.T) {
+		var tar LeaveVis
+		GetWalker(r.TypeOf(``), TypeFilter[string]{}).Walk(r.ValueOf(`val`), &tar)
+		eq(t, LeaveVis{`enter val`}, tar)
+	})
+}
+
+func TestWalkParallel(t *testing.T) {
+	src := make([][]Outer, 4)
+	for ind := range src {
+		src[ind] = testSlice
+	}
+	total := int64(len(src) * len(testSlice) * 6)
+
+	t.Run(`concurrent`, func(t *testing.T) {
+		for _, workers := range []int{-1, 0, 1, 2, 3, 8, 64} {
+			var tar CountVis
+			WalkParallel(r.ValueOf(src), TypeFilter[string]{}, &tar, workers)
+			eq(t, total, atomic.LoadInt64(&tar.Count))
+		}
+	})
+
+	t.Run(`sequential fallback`, func(t *testing.T) {
+		var count int
+		WalkParallel(r.ValueOf(src), TypeFilter[string]{}, VisitorFunc(func(r.Value, r.StructField) {
+			count++
+		}), 8)
+		eq(t, total, int64(count))
+	})
+
+	t.Run(`nil`, func(t *testing.T) {
+		WalkParallel(r.ValueOf(src), TypeFilter[string]{}, nil, 8)
+		WalkParallel(r.Value{}, TypeFilter[string]{}, &CountVis{}, 8)
+		WalkParallel(r.ValueOf(src), nil, PanicConcurrentVis{}, 8)
+	})
+
+	t.Run(`panic`, func(t *testing.T) {
+		panics(t, `panic from visitor`, func() {
+			WalkParallel(r.ValueOf(src), TypeFilter[string]{}, PanicConcurrentVis{}, 8)
+		})
+	})
+
+	// The last chunk is walked in the calling goroutine.
+	t.Run(`panic in last chunk`, func(t *testing.T) {
+		src := make([]int, parChunkMin*8)
+		for ind := range src {
+			src[ind] = ind + 1
+		}
+
+		tar := PanicLastVis{Last: len(src)}
+		panics(t, `panic from visitor`, func() {
+			WalkParallel(r.ValueOf(src), TypeFilter[int]{}, &tar, 8)
+		})
+		eq(t, int64(len(src)), atomic.LoadInt64(&tar.Count))
+	})
+}
+
+func TestUniqueAppender(t *testing.T) {
+	tar := UniqueAppender[string]{Appender: Appender[string]{`one`}}
+
+	tar.Visit(r.ValueOf(``), r.StructField{})
+	tar.Visit(r.ValueOf(`one`), r.StructField{})
+	tar.Visit(r.ValueOf(`two`), r.StructField{})
+	tar.Visit(r.ValueOf(`two`), r.StructField{})
+
+	eq(t, Appender[string]{`one`, `two`}, tar.Appender)
+}
+
+func TestTrawlUnique(t *testing.T) {
+	src := makeTrawlSrc()
+
+	TrawlUnique[TrawlSrc, []string](nil, nil)
+
+	var out []string
+	TrawlUnique(&src, &out)
+	eq(t, []string{`one`, `two`, `three`}, out)
+
+	out = []string{`three`, `four`}
+	TrawlUnique(&src, &out)
+	eq(t, []string{`three`, `four`, `one`, `two`}, out)
+}
+
+func TestTrawlFirst(t *testing.T) {
+	src := makeTrawlSrc()
+
+	test := func(exp string, expOk bool, src *TrawlSrc) {
+		t.Helper()
+		val, ok := TrawlFirst[string](src)
+		eq(t, exp, val)
+		eq(t, expOk, ok)
+	}
+
+	test(``, false, nil)
+	test(``, false, &TrawlSrc{})
+	test(`one`, true, &src)
+
+	src.Strs = nil
+	test(`two`, true, &src)
+
+	num, ok := TrawlFirst[int](&src)
+	eq(t, 10, num)
+	eq(t, true, ok)
+}
+
+func TestCount(t *testing.T) {
+	src := makeTrawlSrc()
+
+	eq(t, 0, Count[string]((*TrawlSrc)(nil)))
+	eq(t, 0, Count[string](&TrawlSrc{}))
+	eq(t, 5, Count[string](&src))
+	eq(t, 1, Count[int](&src))
+	eq(t, 0, Count[float64](&src))
+}
+
+func TestExists(t *testing.T) {
+	src := makeTrawlSrc()
+
+	eq(t, false, Exists[string]((*TrawlSrc)(nil)))
+	eq(t, false, Exists[string](&TrawlSrc{}))
+	eq(t, true, Exists[string](&src))
+	eq(t, true, Exists[int](&src))
+	eq(t, false, Exists[float64](&src))
+}
+
+func TestTrawlPtr(t *testing.T) {
+	src := makeTrawlSrc()
+
+	TrawlPtr[TrawlSrc, []*string](nil, nil)
+
+	var out []*string
+	TrawlPtr(&src, &out)
+	eq(t, 5, len(out))
+	is(t, &src.Strs[0], out[0])
+	is(t, src.Ptr, out[4])
+
+	for _, ptr := range out {
+		*ptr += `!`
+	}
+	eq(t, []string{`one!`, `!`, `two!`, `one!`}, src.Strs)
+	eq(t, `two!`, *src.Ptr)
+	eq(t, any(`three`), src.Iface)
+}
+
+func TestPtrAppender_maps(t *testing.T) {
+	type Src struct {
+		Str  string
+		Strs map[int]string
+		Ptrs map[int]*string
+	}
+
+	src := Src{
+		Str:  `one`,
+		Strs: map[int]string{10: `two`, 20: `three`},
+		Ptrs: map[int]*string{10: stringPtr(`four`)},
+	}
+
+	var tar PtrAppender[string]
+	WalkPtr(&src, Or{tar.Filter(), Maps(VisMap)}, &tar)
+
+	eq(t, 2, len(tar))
+	is(t, &src.Str, tar[0])
+	is(t, src.Ptrs[10], tar[1])
+}
+
+func TestValues(t *testing.T) {
+	src := makeTrawlSrc()
+
+	for range Values(r.Value{}, All{}) {
+		t.Fatal(`unexpected iteration`)
+	}
+
+	var strs []string
+	var names []string
+	for val, field := range Values(r.ValueOf(&src), TypeFilter[string]{}) {
+		strs = append(strs, val.String())
+		names = append(names, field.Name)
+	}
+	eq(t, []string{`one`, ``, `two`, `one`, `two`, `three`}, strs)
+	eq(t, `Iface`, names[len(names)-1])
+
+	strs = nil
+	for val := range Values(r.ValueOf(&src), TypeFilter[string]{}) {
+		strs = append(strs, val.String())
+		if len(strs) == 2 {
+			break
+		}
+	}
+	eq(t, []string{`one`, ``}, strs)
+}
+
+func TestEach(t *testing.T) {
+	src := makeTrawlSrc()
+
+	for range Each[string]((*TrawlSrc)(nil)) {
+		t.Fatal(`unexpected iteration`)
+	}
+
+	for ptr := range Each[string](&src) {
+		*ptr += `!`
+	}
+	eq(t, []string{`one!`, `!`, `two!`, `one!`}, src.Strs)
+	eq(t, `two!`, *src.Ptr)
+	eq(t, any(`three`), src.Iface)
+
+	var count int
+	for ptr := range Each[int](&src) {
+		count++
+		eq(t, 0, *ptr)
+		break
+	}
+	eq(t, 1, count)
+}
+
+func TestDiff(t *testing.T) {
+	fil := And{All{}, Except{FieldNameFilter(`UpdatedAt`)}}
+
+	prev := DiffRec{
+		Id:        10,
+		Name:      `one`,
+		Inner:     &DiffInner{`two`, []string{`three`}},
+		List:      []DiffInner{{Name: `four`}, {Name: `five`}},
+		Meta:      map[string]string{`six`: `seven`},
+		Any:       `eight`,
+		UpdatedAt: time.Unix(1, 0),
+		private:   `nine`,
+	}
+
+	eq(t, []DiffOut(nil), diffOut(Diff(nil, nil, fil)))
+	eq(t, []DiffOut(nil), diffOut(Diff(prev, prev, fil)))
+	eq(t, []DiffOut(nil), diffOut(Diff(prev, prev, nil)))
+	eq(t, []DiffOut{{nil, 10, `10`}}, diffOut(Diff(10, `10`, fil)))
+	eq(t, []DiffOut{{nil, nil, 10}}, diffOut(Diff(nil, 10, fil)))
+	eq(t, []DiffOut{{nil, 10, 20}}, diffOut(Diff(10, 20, fil)))
+
+	next := prev
+	next.Inner = &DiffInner{`two`, []string{`three`, `four`}}
+	next.List = []DiffInner{{Name: `four!`}}
+	next.Meta = map[string]string{`six`: `seven!`}
+	next.Any = 10
+	next.UpdatedAt = time.Unix(2, 0)
+	next.private = `nine!`
+
+	eq(
+		t,
+		[]DiffOut{
+			{Path{2, 1, 1}, nil, `four`},
+			{Path{3, 0, 0}, `four`, `four!`},
+			{Path{3, 1}, DiffInner{Name: `five`}, nil},
+			{Path{4}, prev.Meta, next.Meta},
+			{Path{5}, `eight`, 10},
+		},
+		diffOut(Diff(prev, next, fil)),
+	)
+
+	eq(
+		t,
+		[]DiffOut{{Path{6}, time.Unix(1, 0), time.Unix(2, 0)}},
+		diffOut(Diff(prev, next, TypeFilter[time.Time]{})),
+	)
+
+	eq(
+		t,
+		[]DiffOut{{Path{7}, `nine`, `nine!`}},
+		diffOut(Diff(prev, next, AllowPrivate{And{TypeFilter[string]{}, FieldNameFilter(`private`)}})),
+	)
+
+	t.Run(`cache`, func(t *testing.T) {
+		var cache WalkerCache
+		eq(t, diffOut(Diff(prev, next, fil)), diffOut(cache.Diff(prev, next, fil)))
+
+		// Plans for `DiffRec` and for the dynamic type of `prev.Any`.
+		eq(t, WalkerCacheStats{Len: 2, Builds: 2}, cache.Stats())
+
+		cache.Diff(prev, next, fil)
+		eq(t, 2, cache.Stats().Builds)
+
+		eq(t, 1, cache.Delete(Type[DiffRec]()))
+		cache.Clear()
+		eq(t, 0, cache.Len())
+	})
+
+	t.Run(`pointers`, func(t *testing.T) {
+		next := prev
+		next.Inner = nil
+
+		eq(
+			t,
+			[]DiffOut{{Path{2}, prev.Inner, (*DiffInner)(nil)}},
+			diffOut(Diff(&prev, &next, fil)),
+		)
+
+		eq(t, []DiffOut(nil), diffOut(Diff(prev, next, TypeFilter[int]{})))
+	})
+
+	t.Run(`interfaces`, func(t *testing.T) {
+		next := prev
+		next.Any = DiffInner{Name: `eight`}
+		prev := prev
+		prev.Any = DiffInner{Name: `nine`}
+
+		eq(
+			t,
+			[]DiffOut{{Path{5, 0}, `nine`, `eight`}},
+			diffOut(Diff(prev, next, fil)),
+		)
+
+		next.Any = nil
+		eq(
+			t,
+			[]DiffOut{{Path{5}, DiffInner{Name: `nine`}, nil}},
+			diffOut(Diff(prev, next, fil)),
+		)
+	})
+
+	t.Run(`maps`, func(t *testing.T) {
+		type Src struct {
+			Ints   map[string][]int
+			Nested map[string]map[string]int
+		}
 
-func TestGetWalker_nil(t *testing.T) {
-	isNil(t, GetWalker(nil, nil))
-	isNil(t, GetWalker(r.TypeOf(``), nil))
-	isNil(t, GetWalker(nil, All{}))
-}
+		prev := Src{Ints: map[string][]int{`one`: {10}}}
+		next := Src{Ints: map[string][]int{`one`: {20}}}
 
-func TestGetWalker_caching(t *testing.T) {
-	var filter0 TypeFilter[string]
-	var filter1 TypeFilter[int]
-	typeOuter := r.TypeOf(Outer{})
-	typeInner := r.TypeOf(Inner{})
+		eq(t, []DiffOut{{Path{0}, prev.Ints, next.Ints}}, diffOut(Diff(prev, next, TypeFilter[int]{})))
+		eq(t, []DiffOut(nil), diffOut(Diff(prev, next, TypeFilter[float64]{})))
 
-	is(t, GetWalker(typeOuter, filter0), GetWalker(typeOuter, filter0))
-	is(t, GetWalker(typeOuter, filter1), GetWalker(typeOuter, filter1))
-	is(t, GetWalker(typeInner, filter0), GetWalker(typeInner, filter0))
-	is(t, GetWalker(typeInner, filter1), GetWalker(typeInner, filter1))
-}
+		next = Src{Ints: map[string][]int{}}
+		eq(t, []DiffOut(nil), diffOut(Diff(Src{}, next, TypeFilter[int]{})))
+		eq(t, []DiffOut(nil), diffOut(Diff(map[int]int(nil), map[int]int{}, Self{})))
 
-func Test_walking(t *testing.T) {
-	{
-		var tar Appender[string]
+		prev = Src{Nested: map[string]map[string]int{`one`: nil}}
+		next = Src{Nested: map[string]map[string]int{`one`: {}}}
+		eq(t, []DiffOut{{Path{1}, prev.Nested, next.Nested}}, diffOut(Diff(prev, next, TypeFilter[int]{})))
+	})
 
-		Walk(testOuterVal, tar.Filter(), &tar)
+	t.Run(`cyclic`, func(t *testing.T) {
+		prev := &ValueNode{true, `one`, &ValueNode{true, `two`, nil}}
+		next := &ValueNode{true, `one`, &ValueNode{false, `three`, nil}}
 
 		eq(
 			t,
-			Appender[string]{`embed val`, `embed ptr val`, `outer val`, `inner val`, `inner ptr val`, `outer iface`},
-			tar,
+			[]DiffOut{{Path{2, 1}, `two`, `three`}},
+			diffOut(Diff(prev, next, TypeFilter[string]{})),
 		)
-	}
-
-	{
-		var tar Appender[int]
-
-		Walk(testOuterVal, tar.Filter(), &tar)
 
 		eq(
 			t,
-			Appender[int]{10, 20, 30, 40},
-			tar,
+			[]DiffOut{{Path{2, 0}, true, false}, {Path{2, 1}, `two`, `three`}},
+			diffOut(Diff(prev, next, All{})),
 		)
-	}
+	})
 }
 
-func TestWalkPtr_invalid(t *testing.T) {
-	panics(t, `expected kind ptr`, func() {
-		WalkPtr(0, All{}, PanicVis{})
+func TestClone(t *testing.T) {
+	eq(t, CloneSrc{}, Clone[CloneSrc](nil))
+	eq(t, `one`, Clone(&[]string{`one`}[0]))
+
+	src := makeCloneSrc()
+	out := Clone(&src)
+	eq(t, src, out)
+
+	isNot := func(exp, act any) {
+		t.Helper()
+		if r.ValueOf(exp).UnsafePointer() == r.ValueOf(act).UnsafePointer() {
+			t.Fatalf(`expected distinct memory, got shared %p`, exp)
+		}
+	}
+
+	isNot(src.Ptr, out.Ptr)
+	isNot(src.Strs, out.Strs)
+	isNot(src.Arr[0], out.Arr[0])
+	isNot(src.Dict, out.Dict)
+	isNot(src.Dict[`four`], out.Dict[`four`])
+	isNot(src.Iface, out.Iface)
+	isNot(src.Node, out.Node)
+	isNot(src.Node.Next, out.Node.Next)
+	is(t, &src.private[0], &out.private[0])
+	eq(t, true, src.Time == out.Time)
+
+	*out.Ptr = `two!`
+	out.Strs[0] = `three!`
+	out.Dict[`four`][0] = `five!`
+	out.Iface.([]string)[0] = `six!`
+	out.Node.Next.Name = `ten!`
+	eq(t, makeCloneSrc().Dict, src.Dict)
+	eq(t, `two`, *src.Ptr)
+	eq(t, []string{`three`}, src.Strs)
+	eq(t, []any{`six`}, []any{src.Iface.([]string)[0]})
+	eq(t, `ten`, src.Node.Next.Name)
+}
+
+func TestCloneWith(t *testing.T) {
+	src := makeCloneSrc()
+
+	CloneWith[CloneSrc](nil, &src, All{})
+
+	{
+		out := makeCloneSrc()
+		CloneWith(&out, nil, All{})
+		eq(t, CloneSrc{}, out)
+	}
+
+	{
+		var out CloneSrc
+		CloneWith(&out, &src, nil)
+		eq(t, src, out)
+		is(t, src.Ptr, out.Ptr)
+		is(t, &src.Strs[0], &out.Strs[0])
+	}
+
+	t.Run(`share_and_zero`, func(t *testing.T) {
+		var out CloneSrc
+		CloneWith(&out, &src, Or{
+			And{
+				All{},
+				Except{FieldNameFilter(`Cache`)},
+				Except{FieldNameFilter(`Secret`)},
+			},
+			And{Self{}, FieldNameFilter(`Cache`)},
+		})
+
+		is(t, &src.Cache[0], &out.Cache[0])
+		eq(t, ``, out.Secret)
+		eq(t, src.Str, out.Str)
+		eq(t, src.Strs, out.Strs)
+		if &src.Strs[0] == &out.Strs[0] {
+			t.Fatal(`expected distinct slices`)
+		}
 	})
-	panics(t, `expected kind ptr`, func() {
-		WalkPtr(``, All{}, PanicVis{})
+
+	t.Run(`map_values`, func(t *testing.T) {
+		var out CloneSrc
+		CloneWith(&out, &src, Or{Both{}, Maps(VisKey)})
+		eq(t, src.Dict, out.Dict)
+		is(t, &src.Dict[`four`][0], &out.Dict[`four`][0])
+
+		out.Dict[`twelve`] = nil
+		eq(t, 1, len(src.Dict))
 	})
-	panics(t, `expected kind ptr`, func() {
-		WalkPtr(Outer{}, All{}, PanicVis{})
+
+	t.Run(`private`, func(t *testing.T) {
+		var out CloneSrc
+		CloneWith(&out, &src, AllowPrivate{All{}})
+		eq(t, src.private, out.private)
+		if &src.private[0] == &out.private[0] {
+			t.Fatal(`expected distinct slices`)
+		}
 	})
-}
 
-func TestWalkPtr_valid_nil(t *testing.T) {
-	WalkPtr(nil, All{}, PanicVis{})
-	WalkPtr((*Outer)(nil), All{}, PanicVis{})
-}
+	t.Run(`cache`, func(t *testing.T) {
+		var cache WalkerCache
 
-func TestWalkPtr_valid_non_nil(t *testing.T) {
-	testWalkPtr(t, func(val any) {
-		WalkPtr(val, TypeFilter[string]{}, VisitorFunc(func(val r.Value, _ r.StructField) {
-			val.SetString(`val`)
-		}))
+		var out CloneSrc
+		cache.CloneWith(&out, &src, AllMaps{})
+		eq(t, Clone(&src), out)
+		if &src.Strs[0] == &out.Strs[0] {
+			t.Fatal(`expected distinct slices`)
+		}
+
+		// Plans for `CloneSrc` and for the dynamic type of `.Iface`.
+		eq(t, WalkerCacheStats{Len: 2, Builds: 2}, cache.Stats())
+
+		cache.CloneWith(&out, &src, AllMaps{})
+		eq(t, 2, cache.Stats().Builds)
+
+		cache.CloneWith((*CloneSrc)(nil), &src, AllMaps{})
+		cache.CloneWith(&out, (*CloneSrc)(nil), AllMaps{})
+		eq(t, CloneSrc{}, out)
+
+		panics(t, `expected pointers of the same type`, func() {
+			cache.CloneWith(&out, src, AllMaps{})
+		})
+		panics(t, `expected pointers of the same type`, func() {
+			cache.CloneWith(out, src, AllMaps{})
+		})
+
+		eq(t, 1, cache.Delete(Type[CloneSrc]()))
+		cache.Clear()
+		eq(t, 0, cache.Len())
 	})
 }
 
-func TestWalkPtrFunc_invalid(t *testing.T) {
-	panics(t, `expected kind ptr`, func() {
-		WalkPtrFunc(0, All{}, func(r.Value, r.StructField) {})
+func TestRedact(t *testing.T) {
+	fil := Or{TagFilter{`secret`, `true`}, TypeFilter[Password]{}}
+
+	Redact(nil, fil)
+	Redact((*RedactSrc)(nil), fil)
+	panics(t, `expected kind ptr`, func() { Redact(RedactSrc{}, fil) })
+	panics(t, `expected kind ptr`, func() { Redact(RedactSrc{}, nil) })
+
+	{
+		src := makeRedactSrc()
+		Redact(&src, nil)
+		eq(t, makeRedactSrc(), src)
+	}
+
+	src := makeRedactSrc()
+	Redact(&src, fil)
+
+	eq(t, redactedCreds(`one`), src.Creds)
+	eq(t, redactedCreds(`two`), *src.Ptr)
+	eq(t, []RedactCreds{redactedCreds(`three`)}, src.List)
+	eq(t, any(redactedCreds(`four`)), src.Iface)
+	eq(t, any(&RedactCreds{Login: `five`}), src.IfacePtr)
+	eq(t, makeRedactSrc().Dict, src.Dict)
+	eq(t, []string(nil), src.Keys)
+	eq(t, makeRedactSrc().private, src.private)
+
+	t.Run(`maps`, func(t *testing.T) {
+		src := makeRedactSrc()
+		Redact(&src, Or{fil, Maps(VisVal)})
+		eq(t, map[string]RedactCreds{`six`: redactedCreds(`six`)}, src.Dict)
 	})
-	panics(t, `expected kind ptr`, func() {
-		WalkPtrFunc(``, All{}, func(r.Value, r.StructField) {})
+
+	t.Run(`private`, func(t *testing.T) {
+		src := makeRedactSrc()
+		Redact(&src, AllowPrivate{fil})
+		eq(t, redactedCreds(`eight`), src.private)
 	})
-	panics(t, `expected kind ptr`, func() {
-		WalkPtrFunc(Outer{}, All{}, func(r.Value, r.StructField) {})
+
+	// Contents of interfaces and maps have the same depth as in other walks.
+	t.Run(`depth`, func(t *testing.T) {
+		src := makeRedactSrc()
+		Redact(&src, DepthStrFilter(1))
+		eq(t, makeRedactSrc(), src)
+
+		Redact(&src, Or{DepthStrFilter(2), Maps(VisVal)})
+
+		exp := makeRedactSrc()
+		exp.Creds = RedactCreds{Pass: `one_pass`}
+		*exp.Ptr = RedactCreds{Pass: `two_pass`}
+		exp.Iface = RedactCreds{Pass: `four_pass`}
+		exp.IfacePtr = &RedactCreds{}
+		exp.Keys = []string{``}
+		eq(t, exp, src)
+
+		Redact(&src, Or{DepthStrFilter(3), Maps(VisVal)})
+		exp.List = []RedactCreds{{Pass: `three_pass`}}
+		exp.Dict = map[string]RedactCreds{`six`: {Pass: `six_pass`}}
+		eq(t, exp, src)
+	})
+
+	t.Run(`value_method`, func(t *testing.T) {
+		src := []ValuePassword{`one`, `two`}
+		Redact(&src, TypeFilter[ValuePassword]{})
+		eq(t, []ValuePassword{``, ``}, src)
+	})
+
+	t.Run(`value`, func(t *testing.T) {
+		src := makeRedactSrc()
+		Redact(&src, And{ActiveStrFilter{10}, FieldNameFilter(`Token`)})
+
+		exp := makeRedactSrc()
+		exp.List[0].Token = ``
+		exp.Iface = RedactCreds{`four`, ``, `four_pass`}
+		exp.IfacePtr.(*RedactCreds).Token = ``
+		eq(t, exp, src)
 	})
 }
 
-func TestWalkPtrFunc_valid_nil(t *testing.T) {
-	WalkPtrFunc(nil, All{}, nil)
-	WalkPtrFunc((*Outer)(nil), All{}, nil)
-	WalkPtrFunc(nil, All{}, func(r.Value, r.StructField) {})
-	WalkPtrFunc((*Outer)(nil), All{}, func(r.Value, r.StructField) {})
+func TestRedacted(t *testing.T) {
+	fil := AllowPrivate{Or{TagFilter{`secret`, `true`}, TypeFilter[Password]{}, Maps(VisVal)}}
+
+	eq(t, RedactSrc{}, Redacted[RedactSrc](nil, fil))
+
+	src := makeRedactSrc()
+	eq(t, src, Redacted(&src, nil))
+
+	out := Redacted(&src, fil)
+	eq(t, makeRedactSrc(), src)
+
+	eq(t, redactedCreds(`one`), out.Creds)
+	eq(t, redactedCreds(`two`), *out.Ptr)
+	eq(t, []RedactCreds{redactedCreds(`three`)}, out.List)
+	eq(t, any(redactedCreds(`four`)), out.Iface)
+	eq(t, any(&RedactCreds{Login: `five`}), out.IfacePtr)
+	eq(t, map[string]RedactCreds{`six`: redactedCreds(`six`)}, out.Dict)
+	eq(t, []string(nil), out.Keys)
+	eq(t, redactedCreds(`eight`), out.private)
 }
 
-func TestWalkPtrFunc_valid_non_nil(t *testing.T) {
-	testWalkPtr(t, func(val any) {
-		WalkPtrFunc(val, TypeFilter[string]{}, func(val r.Value, _ r.StructField) {
-			val.SetString(`val`)
-		})
-	})
+func TestTrawlParallel(t *testing.T) {
+	var exp []string
+	Trawl(&testSlice, &exp)
+
+	for _, workers := range []int{1, 2, 8} {
+		var act []string
+		TrawlParallel(&testSlice, &act, workers)
+		eq(t, len(exp), len(act))
+
+		sort.Strings(exp)
+		sort.Strings(act)
+		eq(t, exp, act)
+	}
+
+	{
+		var act []int
+		TrawlParallelWith(&testSlice, &act, TagFilter{`db`, `embed_num`}, 4)
+		eq(t, len(testSlice)*2, len(act))
+	}
 }
 
-func testWalkPtr(t testing.TB, fun func(any)) {
-	var tar Outer
-	fun(&tar)
+func TestRewrite(t *testing.T) {
+	type Src struct {
+		Str   string
+		Ptr   *string
+		Strs  []string
+		Iface any
+		Dict  map[string]string
+		Time  time.Time
+		Num   int
+	}
 
-	var exp Outer
-	exp.Embed.EmbedStr = `val`
-	exp.OuterStr = `val`
-	exp.Inner.InnerStr = `val`
+	str := ` four `
+	src := Src{
+		Str:   ` one `,
+		Ptr:   &str,
+		Strs:  []string{` two `, ` three `},
+		Iface: ` five `,
+		Dict:  map[string]string{` six `: ` seven `},
+		Time:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone(``, 3600)),
+		Num:   10,
+	}
 
-	eq(t, exp, tar)
+	Rewrite[string, Src](nil, strings.TrimSpace)
+	Rewrite[string](&src, nil)
+	eq(t, ` one `, src.Str)
+
+	Rewrite(&src, strings.TrimSpace)
+	eq(t, `one`, src.Str)
+	eq(t, `four`, str)
+	eq(t, []string{`two`, `three`}, src.Strs)
+	eq(t, any(` five `), src.Iface)
+	eq(t, map[string]string{` six `: ` seven `}, src.Dict)
+	eq(t, 10, src.Num)
+
+	RewritePtr(&src, func(val *time.Time) { *val = val.UTC() })
+	eq(t, time.Date(2024, 1, 2, 2, 4, 5, 0, time.UTC), src.Time)
+
+	RewritePtr(&src, func(val *int) { *val *= 2 })
+	eq(t, 20, src.Num)
+
+	RewritePtr[int, Src](nil, func(val *int) { *val *= 2 })
+	RewritePtr[int](&src, nil)
+	eq(t, 20, src.Num)
+
+	{
+		str := `one`
+		Rewrite(&str, strings.ToUpper)
+		eq(t, `ONE`, str)
+	}
 }
 
-/**
-The inner value is missing because we currently don't walk inner occurrences of
-a cyclic type, only the outermost occurrence. This is a technical limitation
-and we would like to fix this in the future.
-*/
-func Test_walking_cyclic_by_ptr(t *testing.T) {
-	type Type = CyclicByPtr
+func TestExplain(t *testing.T) {
+	eq(t, ``, Explain(nil, All{}))
+	eq(t, `int [none]`, Explain(Type[int](), nil))
+	eq(t, `int [desc]`, Explain(Type[int](), TypeFilter[string]{}))
+	eq(t, `string [self|desc]`, Explain(Type[string](), TypeFilter[string]{}))
 
-	testGetWalkerCyclic[Type](t)
+	eq(
+		t,
+		strings.Join([]string{
+			`*rf.Outer [desc]`,
+			`	* rf.Outer [desc]`,
+			`		.Embed rf.Embed [desc]`,
+			`			.EmbedNum int [self|desc]`,
+			`		.EmbedPtr *rf.Embed [desc]`,
+			`			* rf.Embed [desc]`,
+			`				.EmbedNum int [self|desc]`,
+			`		.Inner rf.Inner [desc]`,
+			`			.InnerNum int [self|desc]`,
+			`		.InnerPtr *rf.Inner [desc]`,
+			`			* rf.Inner [desc]`,
+			`				.InnerNum int [self|desc]`,
+			`		.OuterIface interface {} [desc] dynamic`,
+		}, "\n"),
+		Explain(Type[*Outer](), TypeFilter[int]{}),
+	)
 
-	testWalkCyclic(t, &Type{
-		Inner: &Type{Value: `inner_val`},
-		Value: `outer_val`,
-	})
+	eq(
+		t,
+		strings.Join([]string{
+			`map[int]string [desc|key|val]`,
+			`	[key] int [self|desc]`,
+		}, "\n"),
+		Explain(Type[map[int]string](), Or{TypeFilter[int]{}, Maps(VisMap)}),
+	)
+
+	eq(
+		t,
+		strings.Join([]string{
+			`[]rf.CyclicByPtr [desc]`,
+			`	[] rf.CyclicByPtr [desc]`,
+			`		.Inner *rf.CyclicByPtr [desc]`,
+			`			* rf.CyclicByPtr [desc]`,
+			`				.Inner *rf.CyclicByPtr [desc] cycle`,
+			`				.Value string [self|desc]`,
+			`		.Value string [self|desc]`,
+		}, "\n"),
+		Explain(Type[[]CyclicByPtr](), TypeFilter[string]{}),
+	)
+
+	eq(
+		t,
+		strings.Join([]string{
+			`rf.Outer [desc]`,
+			`	.OuterIface interface {} [desc] dynamic`,
+			`	.embedPrivate rf.Private [self|desc] private`,
+			`	.private rf.Private [self|desc] private`,
+		}, "\n"),
+		Explain(Type[Outer](), AllowPrivate{TypeFilter[Private]{}}),
+	)
 }
 
-// The inner value is missing for the same reason as with `CyclicByPtr`.
-func Test_walking_cyclic_by_slice(t *testing.T) {
-	type Type = CyclicBySlice
+func TestAllowPrivate(t *testing.T) {
+	src := Outer{
+		OuterStr:     `outer val`,
+		embedPrivate: Private{PrivateStr: `embed private val`},
+		private:      Private{PrivateStr: `private val`},
+	}
 
-	testGetWalkerCyclic[Type](t)
+	t.Run(`excluded by default`, func(t *testing.T) {
+		var tar Appender[string]
+		WalkPtr(&src, tar.Filter(), &tar)
+		eq(t, Appender[string]{`outer val`}, tar)
+	})
 
-	testWalkCyclic(t, &Type{
-		Inner: []Type{{Value: `inner_val`}},
-		Value: `outer_val`,
+	t.Run(`read by pointer`, func(t *testing.T) {
+		var tar Appender[string]
+		WalkPtr(&src, AllowPrivate{tar.Filter()}, &tar)
+		eq(t, Appender[string]{`outer val`, `embed private val`, `private val`}, tar)
 	})
-}
 
-/**
-Unlike pointers and slices, we don't walk maps. In this test, the inner value
-will be missing even if we someday implement proper walking of cyclic types.
-*/
-func Test_walking_cyclic_by_map(t *testing.T) {
-	type Type = CyclicByMap
+	t.Run(`read by value`, func(t *testing.T) {
+		var tar Appender[string]
+		Walk(r.ValueOf(src), AllowPrivate{tar.Filter()}, &tar)
+		eq(t, Appender[string]{`outer val`, `embed private val`, `private val`}, tar)
+	})
 
-	testGetWalkerCyclic[Type](t)
+	t.Run(`read via combinators`, func(t *testing.T) {
+		var tar Appender[string]
+		TrawlWith(&src, &tar, AllowPrivate{All{}})
+		eq(t, Appender[string]{`outer val`, `embed private val`, `private val`}, tar)
 
-	testWalkCyclic(t, &Type{
-		Inner: map[string]CyclicByMap{`inner`: {Value: `inner_val`}},
-		Value: `outer_val`,
+		tar = nil
+		Walk(r.ValueOf(src), Or{nil, AllowPrivate{tar.Filter()}}, &tar)
+		eq(t, Appender[string]{`outer val`, `embed private val`, `private val`}, tar)
+	})
+
+	t.Run(`custom`, func(t *testing.T) {
+		var tar Appender[string]
+		WalkPtr(&src, PrivateStrFilter{}, &tar)
+		eq(t, Appender[string]{`outer val`, `private val`}, tar)
+	})
+
+	t.Run(`modify`, func(t *testing.T) {
+		tar := src
+
+		WalkPtrFunc(&tar, AllowPrivate{TypeFilter[string]{}}, func(val r.Value, _ r.StructField) {
+			val.SetString(val.String() + ` modified`)
+		})
+
+		eq(t, `outer val modified`, tar.OuterStr)
+		eq(t, `embed private val modified`, tar.embedPrivate.PrivateStr)
+		eq(t, `private val modified`, tar.private.PrivateStr)
+		eq(t, `private val`, src.private.PrivateStr)
+	})
+
+	t.Run(`AllowPrivate nil`, func(t *testing.T) {
+		eq(t, byte(VisNone), AllowPrivate{}.Visit(Type[string](), r.StructField{}))
+		eq(t, true, AllowPrivate{}.VisitPrivate(Type[string](), r.StructField{}))
 	})
 }
 
@@ -701,10 +2671,12 @@ func testGetWalkerCyclic[A any](t *testing.T) {
 	)
 }
 
-func testWalkCyclic(t *testing.T, src any) {
+func testWalkCyclic(t *testing.T, exp Appender[string], fil Filter, src any) {
+	t.Helper()
 	var tar Appender[string]
-	WalkPtr(src, tar.Filter(), &tar)
-	eq(t, Appender[string]{`outer_val`}, tar)
+	WalkPtr(src, fil, &tar)
+	sort.Strings(tar)
+	eq(t, exp, tar)
 }
 
 func TestIsEmbed(t *testing.T) {
@@ -724,6 +2696,115 @@ func TestIsEmbed(t *testing.T) {
 	test(false, `OuterDict`)
 }
 
+func TestCache(t *testing.T) {
+	var calls int64
+	cache := Cache{Func: func(typ r.Type) any {
+		atomic.AddInt64(&calls, 1)
+		time.Sleep(time.Millisecond)
+		return typ.String()
+	}}
+
+	testCacheConcurrent(t, `rf.Outer`, func() any { return cache.Get(Type[Outer]()) })
+	eq(t, int64(1), atomic.LoadInt64(&calls))
+
+	eq(t, `rf.Inner`, cache.Get(Type[Inner]()))
+	eq(t, int64(2), atomic.LoadInt64(&calls))
+
+	isNil(t, (&Cache{}).Get(Type[Outer]()))
+}
+
+func TestTypeCache(t *testing.T) {
+	var calls int64
+	cache := TypeCache[string]{Func: func(typ r.Type) string {
+		atomic.AddInt64(&calls, 1)
+		time.Sleep(time.Millisecond)
+		return typ.String()
+	}}
+
+	testCacheConcurrent(t, `rf.Outer`, func() any { return cache.Get(Type[Outer]()) })
+	eq(t, int64(1), atomic.LoadInt64(&calls))
+
+	cache.Prewarm(Type[Outer](), Type[Inner](), Type[Embed]())
+	eq(t, int64(3), atomic.LoadInt64(&calls))
+
+	eq(t, map[r.Type]string{
+		Type[Outer](): `rf.Outer`,
+		Type[Inner](): `rf.Inner`,
+		Type[Embed](): `rf.Embed`,
+	}, typeCacheMap(&cache))
+
+	cache.Delete(Type[Inner]())
+	eq(t, map[r.Type]string{
+		Type[Outer](): `rf.Outer`,
+		Type[Embed](): `rf.Embed`,
+	}, typeCacheMap(&cache))
+
+	eq(t, `rf.Inner`, cache.Get(Type[Inner]()))
+	eq(t, int64(4), atomic.LoadInt64(&calls))
+
+	t.Run(`Range stop`, func(t *testing.T) {
+		var count int
+		cache.Range(func(r.Type, string) bool {
+			count++
+			return false
+		})
+		eq(t, 1, count)
+	})
+
+	t.Run(`zero`, func(t *testing.T) {
+		var cache TypeCache[string]
+		eq(t, ``, cache.Get(Type[Outer]()))
+		cache.Range(nil)
+	})
+
+	t.Run(`panic`, func(t *testing.T) {
+		var fail bool
+		cache := TypeCache[string]{Func: func(typ r.Type) string {
+			if fail {
+				panic(ErrStr(`fail`))
+			}
+			return typ.String()
+		}}
+
+		fail = true
+		panics(t, `fail`, func() { cache.Get(Type[Outer]()) })
+		eq(t, map[r.Type]string{}, typeCacheMap(&cache))
+
+		fail = false
+		eq(t, `rf.Outer`, cache.Get(Type[Outer]()))
+	})
+}
+
+func testCacheConcurrent(t *testing.T, exp any, fun func() any) {
+	t.Helper()
+
+	var group sync.WaitGroup
+	var out [16]any
+
+	for ind := range out {
+		ind := ind
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			out[ind] = fun()
+		}()
+	}
+	group.Wait()
+
+	for _, val := range out {
+		eq(t, exp, val)
+	}
+}
+
+func typeCacheMap[A any](cache *TypeCache[A]) map[r.Type]A {
+	out := map[r.Type]A{}
+	cache.Range(func(key r.Type, val A) bool {
+		out[key] = val
+		return true
+	})
+	return out
+}
+
 func TestFields(t *testing.T) {
 	testFields(t, Fields)
 }
@@ -1223,6 +3304,134 @@ func TestMaybeOr(t *testing.T) {
 	eq(t, Or{Self{}, Desc{}}, MaybeOr(nil, Self{}, nil, Desc{}, nil))
 }
 
+func TestMaybeAnd_many(t *testing.T) {
+	vals := []Filter{All{}, nil, Both{}, All{}, All{}, All{}, All{}, All{}, All{}, Self{}}
+	eq(t, AndOf(vals...), MaybeAnd(vals...))
+	eq(t, byte(VisSelf), MaybeAnd(vals...).Visit(nil, r.StructField{}))
+}
+
+func TestMaybeOr_many(t *testing.T) {
+	vals := []Filter{Self{}, nil, Self{}, Self{}, Self{}, Self{}, Self{}, Self{}, Self{}, Desc{}}
+	eq(t, OrOf(vals...), MaybeOr(vals...))
+	eq(t, byte(VisBoth), MaybeOr(vals...).Visit(nil, r.StructField{}))
+}
+
+func TestAndOf(t *testing.T) {
+	test := func(exp byte, val AndList) {
+		t.Helper()
+		eq(t, exp, val.Visit(nil, r.StructField{}))
+	}
+
+	test(VisNone, AndList{})
+	test(VisNone, AndOf())
+	test(VisNone, AndOf(nil, nil))
+	test(VisAll, AndOf(All{}))
+	test(VisSelf, AndOf(nil, All{}, nil, Both{}, Self{}))
+	test(VisNone, AndOf(Self{}, Desc{}))
+	test(VisDesc, AndOf(All{}, All{}, All{}, All{}, All{}, All{}, All{}, All{}, All{}, Desc{}))
+
+	eq(t, AndList{}, AndOf(nil))
+	eq(t, AndOf(Self{}, Desc{}), AndOf(nil, Self{}, nil, Desc{}))
+	eq(t, []Filter{Self{}, Desc{}}, AndOf(nil, Self{}, nil, Desc{}).Filters())
+	eq(t, []Filter(nil), AndList{}.Filters())
+
+	if AndOf(Self{}, Desc{}) == AndOf(Desc{}, Self{}) {
+		t.Fatalf(`expected different lists of filters to produce different handles`)
+	}
+
+	panics(t, `invalid filter`, func() { AndOf(All{}, InvalidFilter{}) })
+}
+
+func TestOrOf(t *testing.T) {
+	test := func(exp byte, val OrList) {
+		t.Helper()
+		eq(t, exp, val.Visit(nil, r.StructField{}))
+	}
+
+	test(VisNone, OrList{})
+	test(VisNone, OrOf())
+	test(VisNone, OrOf(nil, nil))
+	test(VisAll, OrOf(All{}))
+	test(VisBoth, OrOf(nil, Self{}, nil, Desc{}))
+	test(VisSelf, OrOf(Self{}, Self{}, Self{}, Self{}, Self{}, Self{}, Self{}, Self{}, Maps(VisMap)))
+
+	eq(t, OrOf(Self{}, Desc{}), OrOf(nil, Self{}, nil, Desc{}))
+	eq(t, []Filter{Self{}, Desc{}}, OrOf(Self{}, Desc{}).Filters())
+}
+
+func Test_filter_lists_walking(t *testing.T) {
+	filters := make([]Filter, 0, 32)
+	for range Iter(cap(filters) - 1) {
+		filters = append(filters, Desc{})
+	}
+
+	var tar Appender[string]
+	Walk(testOuterVal, OrOf(append(filters, TypeFilter[string]{})...), &tar)
+
+	eq(
+		t,
+		Appender[string]{`embed val`, `embed ptr val`, `outer val`, `inner val`, `inner ptr val`, `outer iface`},
+		tar,
+	)
+
+	is(t, GetWalker(Type[Outer](), OrOf(filters...)), GetWalker(Type[Outer](), OrOf(filters...)))
+
+	// Filter lists are read without locks or allocations.
+	val := r.ValueOf(`one`)
+	and := AndOf(append(filters, ActiveStrFilter{3})...)
+	or := OrOf(append(filters, ActiveStrFilter{3})...)
+
+	eq(t, byte(VisSelf), AndOf(Self{}, ActiveStrFilter{3}).VisitValue(val, r.StructField{}))
+	eq(t, byte(VisNone), and.VisitValue(val, r.StructField{}))
+	eq(t, byte(VisBoth), or.VisitValue(val, r.StructField{}))
+	eq(t, 0.0, testing.AllocsPerRun(100, func() { and.VisitValue(val, r.StructField{}) }))
+	eq(t, 0.0, testing.AllocsPerRun(100, func() { or.VisitValue(val, r.StructField{}) }))
+}
+
+func TestNot(t *testing.T) {
+	test := func(exp byte, val Not) {
+		t.Helper()
+		eq(t, exp, val.Visit(nil, r.StructField{}))
+	}
+
+	test(0b_0000_0000, Not{})
+	test(0b_0000_0011, Not{Not{}})
+	test(0b_0000_0010, Not{Self{}})
+	test(0b_0000_0001, Not{Desc{}})
+	test(0b_0000_0000, Not{Both{}})
+	test(0b_1111_1100, Not{All{}})
+	test(0b_1111_1100, Not{AllMaps{}})
+	test(0b_0000_0011, Not{Maps(VisMap)})
+}
+
+func TestXor(t *testing.T) {
+	test := func(exp byte, val Xor) {
+		t.Helper()
+		eq(t, exp, val.Visit(nil, r.StructField{}))
+	}
+
+	test(0b_0000_0000, Xor{})
+	test(0b_0000_0001, Xor{Self{}})
+	test(0b_0000_0001, Xor{nil, Self{}})
+	test(0b_0000_0000, Xor{Self{}, Self{}})
+	test(0b_0000_0011, Xor{Self{}, Desc{}})
+	test(0b_0000_0010, Xor{Self{}, Both{}})
+	test(0b_1111_1100, Xor{Both{}, All{}})
+	test(0b_1111_1100, Xor{Both{}, AllMaps{}})
+}
+
+func Test_combinators_VisitPrivate(t *testing.T) {
+	field := r.StructField{Name: `private`}
+
+	eq(t, true, AndOf(Self{}, AllowPrivate{}).VisitPrivate(nil, field))
+	eq(t, false, AndOf(Self{}, Desc{}).VisitPrivate(nil, field))
+	eq(t, true, OrOf(Self{}, AllowPrivate{}).VisitPrivate(nil, field))
+	eq(t, true, Not{AllowPrivate{}}.VisitPrivate(nil, field))
+	eq(t, false, Not{Self{}}.VisitPrivate(nil, field))
+	eq(t, true, Xor{Self{}, AllowPrivate{}}.VisitPrivate(nil, field))
+	eq(t, false, Xor{Self{}, Desc{}}.VisitPrivate(nil, field))
+}
+
 func Test_reflect_Type_FieldByIndex(t *testing.T) {
 	typ := r.TypeOf(struct {
 		One [4]string